@@ -23,6 +23,7 @@ import (
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	"github.com/pkg/errors"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -32,8 +33,10 @@ import (
 )
 
 var (
-	DeploymentKind = apps.SchemeGroupVersion.WithKind("Deployment")
-	CloneSetKind   = kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet")
+	DeploymentKind            = apps.SchemeGroupVersion.WithKind("Deployment")
+	ReplicaSetKind            = apps.SchemeGroupVersion.WithKind("ReplicaSet")
+	ReplicationControllerKind = corev1.SchemeGroupVersion.WithKind("ReplicationController")
+	CloneSetKind              = kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet")
 )
 
 var managerOnce sync.Once
@@ -66,13 +69,13 @@ func GetScheme() *runtime.Scheme {
 
 type ResourceRef struct {
 	// API version of the object.
-	APIVersion string
+	APIVersion string `json:"apiVersion"`
 	// Kind of the object.
-	Kind string
+	Kind string `json:"kind"`
 	// Namespace of the object.
-	Namespace string
+	Namespace string `json:"namespace"`
 	// Name of the object.
-	Name string
+	Name string `json:"name"`
 }
 
 func (rf *ResourceRef) GetGroupVersionKind() schema.GroupVersionKind {
@@ -92,6 +95,24 @@ func NewDeploymentRef(namespace, name string) ResourceRef {
 	}
 }
 
+func NewReplicaSetRef(namespace, name string) ResourceRef {
+	return ResourceRef{
+		APIVersion: ReplicaSetKind.GroupVersion().String(),
+		Kind:       ReplicaSetKind.Kind,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+func NewReplicationControllerRef(namespace, name string) ResourceRef {
+	return ResourceRef{
+		APIVersion: ReplicationControllerKind.GroupVersion().String(),
+		Kind:       ReplicationControllerKind.Kind,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
 func NewCloneSetRef(namespace, name string) ResourceRef {
 	return ResourceRef{
 		APIVersion: CloneSetKind.GroupVersion().String(),
@@ -18,7 +18,12 @@ package cmd
 
 import (
 	"flag"
+	kcreate "github.com/openkruise/kruise-tools/pkg/cmd/create"
+	kget "github.com/openkruise/kruise-tools/pkg/cmd/get"
 	"github.com/openkruise/kruise-tools/pkg/cmd/migrate"
+	"github.com/openkruise/kruise-tools/pkg/cmd/preheat"
+	"github.com/openkruise/kruise-tools/pkg/cmd/recreate"
+	"github.com/openkruise/kruise-tools/pkg/cmd/sidecarset"
 	"io"
 	"os"
 
@@ -378,7 +383,12 @@ func NewKubectlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 		{
 			Message: "Basic Commands:",
 			Commands: []*cobra.Command{
+				kcreate.NewCmdCreate(f, ioStreams),
+				kget.NewCmdGet(f, ioStreams),
 				kset.NewCmdSet(f, ioStreams),
+				preheat.NewCmdPreheat(f, ioStreams),
+				recreate.NewCmdRecreate(f, ioStreams),
+				sidecarset.NewCmdSidecarSet(f, ioStreams),
 			},
 		},
 		{
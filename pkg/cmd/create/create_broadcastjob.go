@@ -0,0 +1,360 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	broadcastJobLong = templates.LongDesc(i18n.T(`
+		Create a broadcastjob with the specified name, which runs a pod on every matching node.`))
+
+	broadcastJobExample = templates.Examples(`
+		# Run a command on every node
+		kubectl-kruise create broadcastjob collect-logs --image=busybox -- sh -c 'du -sh /var/log'
+
+		# Warm up a cache on the nodes labeled role=cache, two nodes at a time, and wait for the result of each node
+		kubectl-kruise create broadcastjob warmup --image=warmup:v1 --node-selector=role=cache --parallelism=2 --wait
+
+		# Print the broadcastjob to be created without creating it
+		kubectl-kruise create broadcastjob warmup --image=warmup:v1 --ttl=1h --dry-run=client -o yaml`)
+)
+
+// minWaitTTL is the shortest --ttl allowed with --wait, so that the result of each node can be read
+// before the broadcastjob and its pods are deleted.
+const minWaitTTL = 30 * time.Second
+
+// CreateBroadcastJobOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type CreateBroadcastJobOptions struct {
+	PrintFlags *genericclioptions.PrintFlags
+
+	PrintObj func(obj runtime.Object) error
+
+	Name         string
+	Image        string
+	Command      []string
+	NodeSelector string
+	Parallelism  string
+	TTL          time.Duration
+	Restart      string
+	Wait         bool
+	Timeout      time.Duration
+
+	Namespace      string
+	Client         client.Client
+	DryRunStrategy cmdutil.DryRunStrategy
+	DryRunVerifier *resource.DryRunVerifier
+
+	genericclioptions.IOStreams
+}
+
+// NewCreateBroadcastJobOptions returns an initialized CreateBroadcastJobOptions instance
+func NewCreateBroadcastJobOptions(streams genericclioptions.IOStreams) *CreateBroadcastJobOptions {
+	return &CreateBroadcastJobOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("created").WithTypeSetter(internalapi.GetScheme()),
+		Restart:    string(corev1.RestartPolicyNever),
+		IOStreams:  streams,
+	}
+}
+
+// NewCmdCreateBroadcastJob returns a Command instance for 'create broadcastjob' sub command
+func NewCmdCreateBroadcastJob(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewCreateBroadcastJobOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "broadcastjob NAME --image=image [--node-selector=key=value] [--parallelism=N] [--ttl=duration] [--wait] -- [COMMAND] [args...]",
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"bcj"},
+		Short:                 i18n.T("Create a broadcastjob with the specified name"),
+		Long:                  broadcastJobLong,
+		Example:               broadcastJobExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+	cmdutil.AddDryRunFlag(cmd)
+	cmd.Flags().StringVar(&o.Image, "image", o.Image, "Image name to run.")
+	cmd.Flags().StringVar(&o.NodeSelector, "node-selector", o.NodeSelector, "Run on the nodes with these labels only, e.g. role=cache,zone=a.")
+	cmd.Flags().StringVar(&o.Parallelism, "parallelism", o.Parallelism, "The number or percentage of nodes to run on at the same time. Defaults to all the nodes.")
+	cmd.Flags().DurationVar(&o.TTL, "ttl", o.TTL, "The length of time to keep the broadcastjob after it finishes, zero means forever. Must be at least 30s with --wait.")
+	cmd.Flags().StringVar(&o.Restart, "restart", o.Restart, "The restart policy of the pods. Supported values: Never, OnFailure.")
+	cmd.Flags().BoolVar(&o.Wait, "wait", o.Wait, "Wait for the broadcastjob to finish and print the result of each node.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The length of time to wait with --wait, zero means never. Any other values should contain a corresponding time unit (e.g. 1s, 2m, 3h).")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *CreateBroadcastJobOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) == 0 || cmd.ArgsLenAtDash() == 0 {
+		return cmdutil.UsageErrorf(cmd, "NAME is required")
+	}
+	o.Name = args[0]
+	if len(args) > 1 {
+		o.Command = args[1:]
+	}
+
+	var err error
+	o.Namespace, _, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	// the client discovers the server when created, which is not needed by client dry-run
+	if o.DryRunStrategy != cmdutil.DryRunClient {
+		clientConfig, err := f.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		o.Client, err = client.New(clientConfig, client.Options{Scheme: internalapi.GetScheme()})
+		if err != nil {
+			return err
+		}
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	o.PrintObj = func(obj runtime.Object) error {
+		return printer.PrintObj(obj, o.Out)
+	}
+	return nil
+}
+
+// Validate makes sure provided values in CreateBroadcastJobOptions are valid
+func (o *CreateBroadcastJobOptions) Validate() error {
+	if len(o.Image) == 0 {
+		return fmt.Errorf("--image must be specified")
+	}
+	if o.Restart != string(corev1.RestartPolicyNever) && o.Restart != string(corev1.RestartPolicyOnFailure) {
+		return fmt.Errorf("invalid --restart %q: must be Never or OnFailure", o.Restart)
+	}
+	if len(o.NodeSelector) > 0 {
+		if _, err := labels.ConvertSelectorToLabelsMap(o.NodeSelector); err != nil {
+			return fmt.Errorf("invalid --node-selector %q: %v", o.NodeSelector, err)
+		}
+	}
+	if len(o.Parallelism) > 0 {
+		parallelism := intstr.Parse(o.Parallelism)
+		if value, err := intstr.GetValueFromIntOrPercent(&parallelism, 100, true); err != nil || value < 1 {
+			return fmt.Errorf("invalid --parallelism %q: must be a positive number or percentage", o.Parallelism)
+		}
+	}
+	if o.TTL < 0 {
+		return fmt.Errorf("--ttl must not be negative")
+	}
+	if o.Wait && o.TTL > 0 && o.TTL < minWaitTTL {
+		return fmt.Errorf("--ttl must be at least %v with --wait, or the broadcastjob may be deleted before its result is read", minWaitTTL)
+	}
+	return nil
+}
+
+// Run performs the execution of 'create broadcastjob' sub command
+func (o *CreateBroadcastJobOptions) Run() error {
+	job, err := o.createBroadcastJob()
+	if err != nil {
+		return err
+	}
+
+	if o.DryRunStrategy != cmdutil.DryRunClient {
+		var createOptions []client.CreateOption
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			if err := o.DryRunVerifier.HasSupport(job.GroupVersionKind()); err != nil {
+				return err
+			}
+			createOptions = append(createOptions, client.DryRunAll)
+		}
+		if err := o.Client.Create(context.TODO(), job, createOptions...); err != nil {
+			return fmt.Errorf("failed to create broadcastjob: %v", err)
+		}
+	}
+	if err := o.PrintObj(job); err != nil {
+		return err
+	}
+
+	if !o.Wait || o.DryRunStrategy != cmdutil.DryRunNone {
+		return nil
+	}
+	name := job.Name
+	if job, err = o.waitForBroadcastJob(job); err != nil {
+		// only a finished broadcastjob is deleted by its ttl
+		if apierrors.IsNotFound(err) && o.TTL > 0 {
+			fmt.Fprintf(o.ErrOut, "broadcastjob %s finished and was deleted after its ttl, the result of each node is no longer available\n", name)
+			return nil
+		}
+		return err
+	}
+	if err := o.printNodeResults(job); err != nil {
+		return err
+	}
+	if job.Status.Phase == kruiseappsv1alpha1.PhaseFailed {
+		return fmt.Errorf("broadcastjob %s failed on %d of %d nodes", job.Name, job.Status.Failed, job.Status.Desired)
+	}
+	return nil
+}
+
+func (o *CreateBroadcastJobOptions) createBroadcastJob() (*kruiseappsv1alpha1.BroadcastJob, error) {
+	job := &kruiseappsv1alpha1.BroadcastJob{
+		TypeMeta: metav1.TypeMeta{APIVersion: kruiseappsv1alpha1.SchemeGroupVersion.String(), Kind: "BroadcastJob"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      o.Name,
+			Namespace: o.Namespace,
+		},
+		Spec: kruiseappsv1alpha1.BroadcastJobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    o.Name,
+							Image:   o.Image,
+							Command: o.Command,
+						},
+					},
+					RestartPolicy: corev1.RestartPolicy(o.Restart),
+				},
+			},
+			CompletionPolicy: kruiseappsv1alpha1.CompletionPolicy{Type: kruiseappsv1alpha1.Always},
+		},
+	}
+	if len(o.NodeSelector) > 0 {
+		nodeSelector, err := labels.ConvertSelectorToLabelsMap(o.NodeSelector)
+		if err != nil {
+			return nil, err
+		}
+		job.Spec.Template.Spec.NodeSelector = nodeSelector
+	}
+	if len(o.Parallelism) > 0 {
+		parallelism := intstr.Parse(o.Parallelism)
+		job.Spec.Parallelism = &parallelism
+	}
+	if o.TTL > 0 {
+		ttl := int32(o.TTL.Seconds())
+		job.Spec.CompletionPolicy.TTLSecondsAfterFinished = &ttl
+	}
+	return job, nil
+}
+
+// waitForBroadcastJob polls the broadcastjob until it completes or fails.
+func (o *CreateBroadcastJobOptions) waitForBroadcastJob(job *kruiseappsv1alpha1.BroadcastJob) (*kruiseappsv1alpha1.BroadcastJob, error) {
+	key := types.NamespacedName{Namespace: job.Namespace, Name: job.Name}
+	condition := func() (bool, error) {
+		current := &kruiseappsv1alpha1.BroadcastJob{}
+		if err := o.Client.Get(context.TODO(), key, current); err != nil {
+			return false, err
+		}
+		job = current
+		return job.Status.Phase == kruiseappsv1alpha1.PhaseCompleted || job.Status.Phase == kruiseappsv1alpha1.PhaseFailed, nil
+	}
+
+	var err error
+	if o.Timeout > 0 {
+		err = wait.PollImmediate(2*time.Second, o.Timeout, condition)
+	} else {
+		err = wait.PollImmediateInfinite(2*time.Second, condition)
+	}
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("timed out waiting for broadcastjob %s: %d succeeded, %d failed, %d active of %d nodes",
+			job.Name, job.Status.Succeeded, job.Status.Failed, job.Status.Active, job.Status.Desired)
+	}
+	return job, err
+}
+
+// printNodeResults prints the result of the pod of the broadcastjob on each node.
+func (o *CreateBroadcastJobOptions) printNodeResults(job *kruiseappsv1alpha1.BroadcastJob) error {
+	podList := &corev1.PodList{}
+	if err := o.Client.List(context.TODO(), podList, client.InNamespace(job.Namespace)); err != nil {
+		return err
+	}
+	var pods []*corev1.Pod
+	for i := range podList.Items {
+		if metav1.IsControlledBy(&podList.Items[i], job) {
+			pods = append(pods, &podList.Items[i])
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Spec.NodeName < pods[j].Spec.NodeName })
+
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	fmt.Fprintln(w, "NODE\tPOD\tPHASE\tRESULT")
+	for _, pod := range pods {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", pod.Spec.NodeName, pod.Name, pod.Status.Phase, podResult(pod))
+	}
+	return nil
+}
+
+// podResult describes how the containers of the pod terminated.
+func podResult(pod *corev1.Pod) string {
+	for _, c := range pod.Status.ContainerStatuses {
+		if terminated := c.State.Terminated; terminated != nil {
+			result := fmt.Sprintf("ExitCode:%d", terminated.ExitCode)
+			if len(terminated.Reason) > 0 {
+				result += " " + terminated.Reason
+			}
+			return result
+		}
+		if waiting := c.State.Waiting; waiting != nil && len(waiting.Reason) > 0 {
+			return waiting.Reason
+		}
+	}
+	if len(pod.Status.Reason) > 0 {
+		return pod.Status.Reason
+	}
+	return "<none>"
+}
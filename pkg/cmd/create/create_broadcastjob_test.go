@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCreateBroadcastJob(t *testing.T) {
+	o := &CreateBroadcastJobOptions{
+		Name:         "warmup",
+		Namespace:    "default",
+		Image:        "busybox",
+		Command:      []string{"sh", "-c", "echo hello"},
+		NodeSelector: "role=cache,zone=a",
+		Parallelism:  "20%",
+		TTL:          time.Hour,
+		Restart:      string(corev1.RestartPolicyNever),
+	}
+	assert.NoError(t, o.Validate())
+
+	job, err := o.createBroadcastJob()
+	assert.NoError(t, err)
+	assert.Equal(t, "warmup", job.Name)
+	assert.Equal(t, "default", job.Namespace)
+	assert.Equal(t, []corev1.Container{{Name: "warmup", Image: "busybox", Command: []string{"sh", "-c", "echo hello"}}}, job.Spec.Template.Spec.Containers)
+	assert.Equal(t, map[string]string{"role": "cache", "zone": "a"}, job.Spec.Template.Spec.NodeSelector)
+	assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+	assert.Equal(t, intstr.FromString("20%"), *job.Spec.Parallelism)
+	assert.Equal(t, kruiseappsv1alpha1.Always, job.Spec.CompletionPolicy.Type)
+	assert.Equal(t, int32(3600), *job.Spec.CompletionPolicy.TTLSecondsAfterFinished)
+
+	o.Parallelism = "0"
+	assert.Error(t, o.Validate())
+	o.Parallelism = ""
+	o.Restart = string(corev1.RestartPolicyAlways)
+	assert.Error(t, o.Validate())
+
+	o.Restart = string(corev1.RestartPolicyNever)
+	o.Wait = true
+	assert.NoError(t, o.Validate())
+	o.TTL = 10 * time.Second
+	assert.Error(t, o.Validate())
+}
+
+func TestWaitForDeletedBroadcastJob(t *testing.T) {
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	o := &CreateBroadcastJobOptions{
+		Name:           "warmup",
+		Namespace:      "default",
+		Image:          "busybox",
+		Restart:        string(corev1.RestartPolicyNever),
+		Wait:           true,
+		Client:         fake.NewFakeClientWithScheme(internalapi.GetScheme()),
+		DryRunStrategy: cmdutil.DryRunNone,
+		IOStreams:      streams,
+	}
+	job, err := o.createBroadcastJob()
+	assert.NoError(t, err)
+
+	// the broadcastjob is gone, as if deleted by its ttl after finishing
+	_, err = o.waitForBroadcastJob(job)
+	assert.True(t, apierrors.IsNotFound(err))
+
+	o.TTL = time.Minute
+	o.PrintObj = func(obj runtime.Object) error {
+		// delete the broadcastjob right after it is created
+		return o.Client.Delete(context.TODO(), obj)
+	}
+	assert.NoError(t, o.Run())
+	assert.Contains(t, errOut.String(), "broadcastjob warmup finished and was deleted after its ttl")
+
+	o.TTL = 0
+	assert.Error(t, o.Run())
+}
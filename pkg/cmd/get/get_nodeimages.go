@@ -0,0 +1,343 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package get
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	nodeImagesLong = templates.LongDesc(i18n.T(`
+		Display the images pulled by the Kruise daemon on each node.
+
+		The NodeImage of every node is read and a matrix of nodes and images is printed,
+		with the pull phase and progress of each image on each node, and the last error
+		of the node. With --image, a row is printed for each node with the pull phase,
+		progress and last message of that image.`))
+
+	nodeImagesExample = templates.Examples(`
+		# List the images on all the nodes
+		kubectl-kruise get nodeimages
+
+		# List the pull phase of an image on all the nodes
+		kubectl-kruise get nodeimages --image=nginx:1.21
+
+		# List the images on a node
+		kubectl-kruise get nodeimages --node=node-a
+
+		# List the nodes which have not pulled an image successfully
+		kubectl-kruise get nodeimages --image=nginx:1.21 --missing`)
+)
+
+// GetNodeImagesOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type GetNodeImagesOptions struct {
+	Image   string
+	Node    string
+	Missing bool
+
+	Reader client.Reader
+
+	genericclioptions.IOStreams
+}
+
+// NewGetNodeImagesOptions returns an initialized GetNodeImagesOptions instance
+func NewGetNodeImagesOptions(streams genericclioptions.IOStreams) *GetNodeImagesOptions {
+	return &GetNodeImagesOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdGetNodeImages returns a Command instance for 'get nodeimages' sub command
+func NewCmdGetNodeImages(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewGetNodeImagesOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "nodeimages [--image=repo:tag] [--node=name] [--missing]",
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"nodeimage", "ni"},
+		Short:                 i18n.T("Display the images pulled on each node"),
+		Long:                  nodeImagesLong,
+		Example:               nodeImagesExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Image, "image", o.Image, "Display this image only, e.g. nginx:1.21 or nginx@sha256:<digest>. The tag defaults to latest.")
+	cmd.Flags().StringVar(&o.Node, "node", o.Node, "Display the images on this node only.")
+	cmd.Flags().BoolVar(&o.Missing, "missing", o.Missing, "List the nodes which have not pulled the image given by --image successfully.")
+	return cmd
+}
+
+// Validate makes sure provided values in GetNodeImagesOptions are valid
+func (o *GetNodeImagesOptions) Validate() error {
+	if o.Missing && len(o.Image) == 0 {
+		return fmt.Errorf("--missing requires --image")
+	}
+	return nil
+}
+
+// Run performs the execution of 'get nodeimages' sub command
+func (o *GetNodeImagesOptions) Run() error {
+	if o.Reader == nil {
+		o.Reader = util.BaseClient().Reader
+	}
+
+	var nodeImages []kruiseappsv1alpha1.NodeImage
+	if len(o.Node) > 0 {
+		nodeImage := kruiseappsv1alpha1.NodeImage{}
+		if err := o.Reader.Get(context.TODO(), types.NamespacedName{Name: o.Node}, &nodeImage); err != nil {
+			return err
+		}
+		nodeImages = append(nodeImages, nodeImage)
+	} else {
+		nodeImageList := &kruiseappsv1alpha1.NodeImageList{}
+		if err := o.Reader.List(context.TODO(), nodeImageList); err != nil {
+			return err
+		}
+		nodeImages = nodeImageList.Items
+	}
+
+	var repo, tag string
+	if len(o.Image) > 0 {
+		repo, tag = splitImage(o.Image)
+	}
+
+	var rows []nodeImageRow
+	if o.Missing {
+		rows = missingNodeImageRows(nodeImages, repo, tag)
+	} else {
+		rows = nodeImageRows(nodeImages, repo, tag)
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(o.ErrOut, "No resources found")
+		return nil
+	}
+
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	if len(o.Image) == 0 {
+		printNodeImageMatrix(w, rows)
+		return nil
+	}
+	fmt.Fprintln(w, "NODE\tIMAGE\tPHASE\tPROGRESS\tMESSAGE")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", row.node, row.image, util.ValueOrNone(string(row.phase)), row.progress(), util.ValueOrNone(util.FirstLine(row.message)))
+	}
+	return nil
+}
+
+// printNodeImageMatrix prints a line for each node of the rows, sorted by node, with a column for each image
+// and the last error of the node.
+func printNodeImageMatrix(w io.Writer, rows []nodeImageRow) {
+	nodes, images, cells, lastErrors := nodeImageMatrix(rows)
+	fmt.Fprintf(w, "NODE\t%s\tLAST ERROR\n", strings.Join(images, "\t"))
+	for i, node := range nodes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", node, strings.Join(cells[i], "\t"), util.ValueOrNone(lastErrors[i]))
+	}
+}
+
+// nodeImageMatrix pivots the rows into the sorted nodes and images, the cell of each image on each node
+// and the last error of each node, which is the message of the image that failed last on it.
+func nodeImageMatrix(rows []nodeImageRow) (nodes, images []string, cells [][]string, lastErrors []string) {
+	nodeIndex := map[string]int{}
+	imageIndex := map[string]int{}
+	for _, row := range rows {
+		nodeIndex[row.node] = 0
+		imageIndex[row.image] = 0
+	}
+	nodes = sortedKeys(nodeIndex)
+	images = sortedKeys(imageIndex)
+
+	cells = make([][]string, len(nodes))
+	for i := range cells {
+		cells[i] = make([]string, len(images))
+		for j := range cells[i] {
+			cells[i][j] = "<none>"
+		}
+	}
+	lastErrors = make([]string, len(nodes))
+	lastFailed := make([]*nodeImageRow, len(nodes))
+	for i := range rows {
+		row := &rows[i]
+		n := nodeIndex[row.node]
+		cells[n][imageIndex[row.image]] = row.cell()
+		if row.phase != kruiseappsv1alpha1.ImagePhaseFailed || len(row.message) == 0 {
+			continue
+		}
+		if last := lastFailed[n]; last == nil || last.completionTime.Before(row.completionTime) {
+			lastFailed[n] = row
+			lastErrors[n] = row.image + ": " + util.FirstLine(row.message)
+		}
+	}
+	return nodes, images, cells, lastErrors
+}
+
+// nodeImageRow is the pull status of an image tag on a node.
+type nodeImageRow struct {
+	node           string
+	image          string
+	phase          kruiseappsv1alpha1.ImagePullPhase
+	progressValue  int32
+	message        string
+	completionTime *metav1.Time
+}
+
+func (r nodeImageRow) progress() string {
+	switch r.phase {
+	case kruiseappsv1alpha1.ImagePhasePulling:
+		return fmt.Sprintf("%d%%", r.progressValue)
+	case kruiseappsv1alpha1.ImagePhaseSucceeded:
+		return "100%"
+	default:
+		return "<none>"
+	}
+}
+
+// cell is the phase of the row in the node image matrix, with the progress while pulling.
+func (r nodeImageRow) cell() string {
+	if r.phase == kruiseappsv1alpha1.ImagePhasePulling {
+		return fmt.Sprintf("%s(%s)", r.phase, r.progress())
+	}
+	return util.ValueOrNone(string(r.phase))
+}
+
+// nodeImageRows returns a row for each image tag on each node, sorted by node and image,
+// only for the given image if repo is not empty. The tags requested in the spec
+// without any status yet are Waiting.
+func nodeImageRows(nodeImages []kruiseappsv1alpha1.NodeImage, repo, tag string) []nodeImageRow {
+	var rows []nodeImageRow
+	for i := range nodeImages {
+		nodeImage := &nodeImages[i]
+		seen := map[string]bool{}
+		for name, status := range nodeImage.Status.ImageStatuses {
+			for _, tagStatus := range status.Tags {
+				if len(repo) > 0 && (name != repo || tagStatus.Tag != tag) {
+					continue
+				}
+				image := joinImage(name, tagStatus.Tag)
+				seen[image] = true
+				rows = append(rows, nodeImageRow{
+					node:           nodeImage.Name,
+					image:          image,
+					phase:          tagStatus.Phase,
+					progressValue:  tagStatus.Progress,
+					message:        tagStatus.Message,
+					completionTime: tagStatus.CompletionTime,
+				})
+			}
+		}
+		for name, spec := range nodeImage.Spec.Images {
+			for _, tagSpec := range spec.Tags {
+				image := joinImage(name, tagSpec.Tag)
+				if seen[image] || len(repo) > 0 && (name != repo || tagSpec.Tag != tag) {
+					continue
+				}
+				rows = append(rows, nodeImageRow{
+					node:  nodeImage.Name,
+					image: image,
+					phase: kruiseappsv1alpha1.ImagePhaseWaiting,
+				})
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].node != rows[j].node {
+			return rows[i].node < rows[j].node
+		}
+		return rows[i].image < rows[j].image
+	})
+	return rows
+}
+
+// missingNodeImageRows returns a row for each node which has not pulled the given image successfully, sorted by node.
+// The phase is empty if the image is not requested on the node at all.
+func missingNodeImageRows(nodeImages []kruiseappsv1alpha1.NodeImage, repo, tag string) []nodeImageRow {
+	rows := nodeImageRows(nodeImages, repo, tag)
+	found := map[string]bool{}
+	var missing []nodeImageRow
+	for _, row := range rows {
+		found[row.node] = true
+		if row.phase != kruiseappsv1alpha1.ImagePhaseSucceeded {
+			missing = append(missing, row)
+		}
+	}
+	for i := range nodeImages {
+		if !found[nodeImages[i].Name] {
+			missing = append(missing, nodeImageRow{node: nodeImages[i].Name, image: joinImage(repo, tag)})
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].node < missing[j].node })
+	return missing
+}
+
+// splitImage splits the image into its repository and tag, which defaults to latest.
+// Like the Kruise daemon, the digest of a reference like repo@sha256:<digest> is used
+// as its tag, unless the reference has a tag too.
+func splitImage(image string) (string, string) {
+	digest := ""
+	if i := strings.Index(image, "@"); i >= 0 {
+		image, digest = image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	if len(digest) > 0 {
+		return image, digest
+	}
+	return image, "latest"
+}
+
+// joinImage is the reverse of splitImage, a digest tag is joined with @.
+func joinImage(repo, tag string) string {
+	if strings.Contains(tag, ":") {
+		return repo + "@" + tag
+	}
+	return repo + ":" + tag
+}
+
+// sortedKeys returns the sorted keys of m and sets the value of each key to its index.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		m[key] = i
+	}
+	return keys
+}
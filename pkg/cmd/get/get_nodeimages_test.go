@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package get
+
+import (
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testNodeImages() []kruiseappsv1alpha1.NodeImage {
+	return []kruiseappsv1alpha1.NodeImage{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-b"},
+			Spec: kruiseappsv1alpha1.NodeImageSpec{Images: map[string]kruiseappsv1alpha1.ImageSpec{
+				"nginx": {Tags: []kruiseappsv1alpha1.ImageTagSpec{{Tag: "1.21"}}},
+			}},
+			Status: kruiseappsv1alpha1.NodeImageStatus{ImageStatuses: map[string]kruiseappsv1alpha1.ImageStatus{
+				"nginx": {Tags: []kruiseappsv1alpha1.ImageTagStatus{{Tag: "1.21", Phase: kruiseappsv1alpha1.ImagePhaseFailed, Message: "not found"}}},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+			Spec: kruiseappsv1alpha1.NodeImageSpec{Images: map[string]kruiseappsv1alpha1.ImageSpec{
+				"nginx":   {Tags: []kruiseappsv1alpha1.ImageTagSpec{{Tag: "1.21"}}},
+				"busybox": {Tags: []kruiseappsv1alpha1.ImageTagSpec{{Tag: "latest"}}},
+			}},
+			Status: kruiseappsv1alpha1.NodeImageStatus{ImageStatuses: map[string]kruiseappsv1alpha1.ImageStatus{
+				"nginx": {Tags: []kruiseappsv1alpha1.ImageTagStatus{{Tag: "1.21", Phase: kruiseappsv1alpha1.ImagePhaseSucceeded}}},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-c"},
+		},
+	}
+}
+
+func TestNodeImageRows(t *testing.T) {
+	rows := nodeImageRows(testNodeImages(), "", "")
+	assert.Equal(t, []nodeImageRow{
+		{node: "node-a", image: "busybox:latest", phase: kruiseappsv1alpha1.ImagePhaseWaiting},
+		{node: "node-a", image: "nginx:1.21", phase: kruiseappsv1alpha1.ImagePhaseSucceeded},
+		{node: "node-b", image: "nginx:1.21", phase: kruiseappsv1alpha1.ImagePhaseFailed, message: "not found"},
+	}, rows)
+
+	rows = nodeImageRows(testNodeImages(), "busybox", "latest")
+	assert.Equal(t, []nodeImageRow{
+		{node: "node-a", image: "busybox:latest", phase: kruiseappsv1alpha1.ImagePhaseWaiting},
+	}, rows)
+}
+
+func TestMissingNodeImageRows(t *testing.T) {
+	rows := missingNodeImageRows(testNodeImages(), "nginx", "1.21")
+	assert.Equal(t, []nodeImageRow{
+		{node: "node-b", image: "nginx:1.21", phase: kruiseappsv1alpha1.ImagePhaseFailed, message: "not found"},
+		{node: "node-c", image: "nginx:1.21"},
+	}, rows)
+}
+
+func TestSplitImage(t *testing.T) {
+	tests := []struct {
+		image string
+		repo  string
+		tag   string
+	}{
+		{image: "nginx:1.21", repo: "nginx", tag: "1.21"},
+		{image: "nginx", repo: "nginx", tag: "latest"},
+		{image: "registry:5000/library/nginx", repo: "registry:5000/library/nginx", tag: "latest"},
+		{image: "registry:5000/library/nginx:1.21", repo: "registry:5000/library/nginx", tag: "1.21"},
+		{image: "nginx@sha256:abc", repo: "nginx", tag: "sha256:abc"},
+		{image: "registry:5000/library/nginx@sha256:abc", repo: "registry:5000/library/nginx", tag: "sha256:abc"},
+		{image: "nginx:1.21@sha256:abc", repo: "nginx", tag: "1.21"},
+	}
+	for _, test := range tests {
+		repo, tag := splitImage(test.image)
+		assert.Equal(t, test.repo, repo, test.image)
+		assert.Equal(t, test.tag, tag, test.image)
+	}
+	assert.Equal(t, "nginx@sha256:abc", joinImage("nginx", "sha256:abc"))
+	assert.Equal(t, "nginx:1.21", joinImage("nginx", "1.21"))
+}
+
+func TestNodeImageMatrix(t *testing.T) {
+	earlier, later := metav1.NewTime(time.Unix(100, 0)), metav1.NewTime(time.Unix(200, 0))
+	rows := []nodeImageRow{
+		{node: "node-b", image: "nginx:1.21", phase: kruiseappsv1alpha1.ImagePhaseFailed, message: "not found\nretrying", completionTime: &later},
+		{node: "node-b", image: "busybox:latest", phase: kruiseappsv1alpha1.ImagePhaseFailed, message: "timeout", completionTime: &earlier},
+		{node: "node-a", image: "nginx:1.21", phase: kruiseappsv1alpha1.ImagePhasePulling, progressValue: 45},
+		{node: "node-a", image: "redis@sha256:abc", phase: kruiseappsv1alpha1.ImagePhaseSucceeded},
+	}
+	nodes, images, cells, lastErrors := nodeImageMatrix(rows)
+	assert.Equal(t, []string{"node-a", "node-b"}, nodes)
+	assert.Equal(t, []string{"busybox:latest", "nginx:1.21", "redis@sha256:abc"}, images)
+	assert.Equal(t, [][]string{
+		{"<none>", "Pulling(45%)", "Succeeded"},
+		{"Failed", "Failed", "<none>"},
+	}, cells)
+	assert.Equal(t, []string{"", "nginx:1.21: not found"}, lastErrors)
+}
+
+func TestRun(t *testing.T) {
+	var objs []runtime.Object
+	for _, nodeImage := range testNodeImages() {
+		objs = append(objs, nodeImage.DeepCopy())
+	}
+	tests := []struct {
+		name     string
+		image    string
+		node     string
+		missing  bool
+		expected string
+	}{
+		{
+			name: "matrix",
+			expected: "NODE     busybox:latest   nginx:1.21   LAST ERROR\n" +
+				"node-a   Waiting          Succeeded    <none>\n" +
+				"node-b   <none>           Failed       nginx:1.21: not found\n",
+		},
+		{
+			name: "matrix of a node",
+			node: "node-b",
+			expected: "NODE     nginx:1.21   LAST ERROR\n" +
+				"node-b   Failed       nginx:1.21: not found\n",
+		},
+		{
+			name:  "image",
+			image: "nginx:1.21",
+			expected: "NODE     IMAGE        PHASE       PROGRESS   MESSAGE\n" +
+				"node-a   nginx:1.21   Succeeded   100%       <none>\n" +
+				"node-b   nginx:1.21   Failed      <none>     not found\n",
+		},
+		{
+			name:    "missing image",
+			image:   "nginx:1.21",
+			missing: true,
+			expected: "NODE     IMAGE        PHASE    PROGRESS   MESSAGE\n" +
+				"node-b   nginx:1.21   Failed   <none>     not found\n" +
+				"node-c   nginx:1.21   <none>   <none>     <none>\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			o := NewGetNodeImagesOptions(streams)
+			o.Image, o.Node, o.Missing = test.image, test.node, test.missing
+			o.Reader = fake.NewFakeClientWithScheme(api.GetScheme(), objs...)
+			assert.NoError(t, o.Validate())
+			assert.NoError(t, o.Run())
+			assert.Equal(t, test.expected, out.String())
+		})
+	}
+}
@@ -18,16 +18,21 @@ package migrate
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/migration"
 	"github.com/spf13/cobra"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type migrateOptions struct {
-	Namespace string
+	Namespace    string
+	DstNamespace string
 
 	From    string
 	To      string
@@ -36,11 +41,15 @@ type migrateOptions struct {
 	SrcRef  api.ResourceRef
 	DstRef  api.ResourceRef
 
-	IsCreate       bool
-	IsCopy         bool
-	Replicas       int32
-	MaxSurge       int32
-	TimeoutSeconds int32
+	IsCreate         bool
+	IsCopy           bool
+	CopyDependencies bool
+	CopyEmptyClaims  bool
+	Replicas         int32
+	MaxSurge         int32
+	TimeoutSeconds   int32
+	LockHolder       string
+	ReportFile       string
 
 	genericclioptions.IOStreams
 }
@@ -66,6 +75,15 @@ func NewCmdMigrate(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *co
 
 	# Migrate replicas from an existing Deployment to an existing CloneSet.
 	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name cloneset-name --dst-name deployment-name --replicas 10 --max-surge=2
+
+	# Migrate replicas and write the report of all scaling steps into a file.
+	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name deployment-name --dst-name cloneset-name --report report.json
+
+	# Create a CloneSet in namespace apps from an existing Deployment in namespace legacy, with its ConfigMaps, Secrets and ServiceAccount.
+	kubectl-kruise migrate CloneSet --from Deployment -n legacy --src-name deployment-name --dst-namespace apps --create --copy-dependencies
+
+	# Migrate all replicas from an existing ReplicationController to an existing CloneSet.
+	kubectl-kruise migrate CloneSet --from ReplicationController -n default --src-name rc-name --dst-name cloneset-name
 `,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmdutil.CheckErr(o.Complete(f, cmd, args))
@@ -73,15 +91,20 @@ func NewCmdMigrate(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *co
 		},
 	}
 
-	cmd.Flags().StringVar(&o.From, "from", "", "Type of the source workload (e.g. Deployment).")
+	cmd.Flags().StringVar(&o.From, "from", "", "Type of the source workload (e.g. Deployment, ReplicaSet, ReplicationController).")
 	cmd.Flags().StringVar(&o.SrcName, "src-name", "", "Name of the source workload.")
 	cmd.Flags().StringVar(&o.DstName, "dst-name", "", "Name of the destination workload.")
+	cmd.Flags().StringVar(&o.DstNamespace, "dst-namespace", "", "Namespace of the destination workload, defaults to the namespace of source workload.")
 
 	cmd.Flags().BoolVar(&o.IsCreate, "create", false, "Create dst workload with replicas=0 from src workload.")
 	cmd.Flags().BoolVar(&o.IsCopy, "copy", false, "Copy replicas from src workload when create.")
+	cmd.Flags().BoolVar(&o.CopyDependencies, "copy-dependencies", false, "Copy ConfigMaps, Secrets and ServiceAccount referenced by src workload into dst namespace.")
+	cmd.Flags().BoolVar(&o.CopyEmptyClaims, "copy-empty-claims", false, "With --copy-dependencies, also create the PersistentVolumeClaims referenced by src workload in dst namespace. The claims are created from the spec of src claims, without their data.")
 	cmd.Flags().Int32Var(&o.Replicas, "replicas", -1, "The replicas needs to migrate, -1 indicates all replicas in src workload.")
 	cmd.Flags().Int32Var(&o.MaxSurge, "max-surge", 1, "Max surge during migration.")
 	cmd.Flags().Int32Var(&o.TimeoutSeconds, "timeout-seconds", -1, "Timeout seconds for migration, -1 indicates no limited.")
+	cmd.Flags().StringVar(&o.ReportFile, "report", "", "Write the migration report with per-step timeline into this file in JSON format.")
+	cmd.Flags().StringVar(&o.LockHolder, "lock-holder", "", "Identity recorded in the migration lock on workloads, defaults to user@hostname.")
 
 	return cmd
 }
@@ -94,6 +117,9 @@ func (o *migrateOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []
 		return fmt.Errorf("must specify namespace by -n or --namespace")
 	}
 	o.Namespace = namespace
+	if len(o.DstNamespace) == 0 {
+		o.DstNamespace = namespace
+	}
 
 	if len(args) == 0 {
 		return fmt.Errorf("must specify workload type like CloneSet")
@@ -109,31 +135,102 @@ func (o *migrateOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []
 	}
 	if len(o.DstName) == 0 && !o.IsCreate {
 		return fmt.Errorf("must specify --dst-name")
+	} else if len(o.DstName) == 0 {
+		o.DstName = o.SrcName
+	}
+	if o.CopyDependencies && o.DstNamespace == o.Namespace {
+		return fmt.Errorf("--copy-dependencies only works with a different --dst-namespace")
+	}
+	if o.CopyEmptyClaims && !o.CopyDependencies {
+		return fmt.Errorf("--copy-empty-claims only works with --copy-dependencies")
 	}
 
 	switch args[0] {
 	case "CloneSet", "cloneset", "clone":
 		o.To = "CloneSet"
-		o.DstRef = api.NewCloneSetRef(namespace, o.DstName)
+		o.DstRef = api.NewCloneSetRef(o.DstNamespace, o.DstName)
 	default:
 		return fmt.Errorf("currently only supported CloneSet as dst type")
 	}
 
 	switch o.From {
-	case "Deployment", "deployment":
+	case "Deployment", "deployment", "deploy":
 		o.From = "Deployment"
 		o.SrcRef = api.NewDeploymentRef(namespace, o.SrcName)
+	case "ReplicaSet", "replicaset", "rs":
+		o.From = "ReplicaSet"
+		o.SrcRef = api.NewReplicaSetRef(namespace, o.SrcName)
+	case "ReplicationController", "replicationcontroller", "rc":
+		o.From = "ReplicationController"
+		o.SrcRef = api.NewReplicationControllerRef(namespace, o.SrcName)
 	default:
-		return fmt.Errorf("currently only supported Deployment as src type")
+		return fmt.Errorf("currently only supported Deployment, ReplicaSet and ReplicationController as src type")
 	}
 
 	return nil
 }
 
 func (o *migrateOptions) Run(f cmdutil.Factory, cmd *cobra.Command) error {
+	if o.DstNamespace != o.Namespace {
+		if err := o.prepareDstNamespace(f); err != nil {
+			return err
+		}
+	}
+
 	switch o.To {
 	case "CloneSet":
 		return o.migrateCloneSet(f, cmd)
 	}
 	return nil
 }
+
+// prepareDstNamespace copies dependencies into dst namespace if required,
+// and warns the Services in src namespace which will lose endpoints after migration.
+func (o *migrateOptions) prepareDstNamespace(f cmdutil.Factory) error {
+	cfg, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: api.GetScheme()})
+	if err != nil {
+		return err
+	}
+
+	template, err := migration.GetPodTemplate(c, o.SrcRef)
+	if err != nil {
+		return err
+	}
+
+	if o.CopyDependencies {
+		deps := migration.GetDependencies(template)
+		if !o.CopyEmptyClaims {
+			for _, name := range deps.PersistentVolumeClaims.List() {
+				internalcmdutil.Print(fmt.Sprintf("Warning: PersistentVolumeClaim %s is not copied to namespace %s, whose data can't be copied (use --copy-empty-claims to create it empty)",
+					name, o.DstNamespace))
+			}
+			deps.PersistentVolumeClaims = nil
+		}
+		copied, err := migration.CopyDependencies(c, deps, o.Namespace, o.DstNamespace)
+		for _, name := range copied {
+			internalcmdutil.Print(fmt.Sprintf("Copied %s from namespace %s to %s", name, o.Namespace, o.DstNamespace))
+			if strings.HasPrefix(name, "PersistentVolumeClaim/") {
+				claim := strings.TrimPrefix(name, "PersistentVolumeClaim/")
+				internalcmdutil.Print(fmt.Sprintf("Warning: PersistentVolumeClaim %s/%s is created empty, without the data of %s/%s",
+					o.DstNamespace, claim, o.Namespace, claim))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	services, err := migration.GetServicesSelectingPods(c, o.Namespace, template.Labels)
+	if err != nil {
+		return err
+	}
+	for _, svc := range services {
+		internalcmdutil.Print(fmt.Sprintf("Warning: Service %s/%s selects pods of %s/%s, it will lose endpoints after pods migrated to namespace %s",
+			svc.Namespace, svc.Name, o.From, o.SrcName, o.DstNamespace))
+	}
+	return nil
+}
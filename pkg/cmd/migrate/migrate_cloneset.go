@@ -17,7 +17,9 @@ limitations under the License.
 package migrate
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"time"
 
 	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
@@ -26,6 +28,7 @@ import (
 	"github.com/openkruise/kruise-tools/pkg/migration"
 	clonesetmigration "github.com/openkruise/kruise-tools/pkg/migration/cloneset"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
 
@@ -47,7 +50,7 @@ func (o *migrateOptions) migrateCloneSet(f cmdutil.Factory, cmd *cobra.Command)
 			return err
 		}
 
-		internalcmdutil.Print(fmt.Sprintf("Successfully created from %s/%s to %s/%s", o.From, o.SrcName, o.To, o.DstName))
+		internalcmdutil.Print(fmt.Sprintf("Successfully created from %s/%s to %s/%s", o.From, o.SrcRef.GetNamespacedName(), o.To, o.DstRef.GetNamespacedName()))
 
 	} else {
 
@@ -57,7 +60,7 @@ func (o *migrateOptions) migrateCloneSet(f cmdutil.Factory, cmd *cobra.Command)
 			return err
 		}
 
-		opts := migration.Options{}
+		opts := migration.Options{LockHolder: o.LockHolder}
 		if o.Replicas >= 0 {
 			opts.Replicas = &o.Replicas
 		}
@@ -89,8 +92,11 @@ func (o *migrateOptions) migrateCloneSet(f cmdutil.Factory, cmd *cobra.Command)
 			case migration.MigrateSucceeded:
 				internalcmdutil.Print(fmt.Sprintf("Successfully migrated %v replicas from %s/%s to %s/%s",
 					newResult.DstMigratedReplicas, o.From, o.SrcName, o.To, o.DstName))
-				return nil
+				return o.writeReport(ctrl, newResult.ID)
 			case migration.MigrateFailed:
+				if err := o.writeReport(ctrl, newResult.ID); err != nil {
+					internalcmdutil.Print(err.Error())
+				}
 				return fmt.Errorf("failed to migrate: %v", newResult.Message)
 			}
 
@@ -100,3 +106,33 @@ func (o *migrateOptions) migrateCloneSet(f cmdutil.Factory, cmd *cobra.Command)
 
 	return nil
 }
+
+func (o *migrateOptions) writeReport(ctrl migration.Control, ID types.UID) error {
+	report, err := ctrl.Report(ID)
+	if err != nil {
+		return err
+	}
+
+	for i, step := range report.Steps {
+		var waiting time.Duration
+		if step.WaitingDuration != nil {
+			waiting = step.WaitingDuration.Duration
+		}
+		internalcmdutil.Print(fmt.Sprintf("Step %d: %s %s/%s %d -> %d at %s, waited %v, created %d pods, deleted %d pods",
+			i+1, step.Action, step.Workload.Kind, step.Workload.Name, step.FromReplicas, step.ToReplicas,
+			step.StartTime.Format(time.RFC3339), waiting, len(step.CreatedPods), len(step.DeletedPods)))
+	}
+
+	if len(o.ReportFile) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(o.ReportFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	internalcmdutil.Print(fmt.Sprintf("Migration report written to %s", o.ReportFile))
+	return nil
+}
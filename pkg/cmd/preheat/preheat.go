@@ -0,0 +1,312 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preheat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/imagepulljob"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/interrupt"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	preheatLong = templates.LongDesc(i18n.T(`
+		Pre-pull images on nodes before they are needed.
+
+		An ImagePullJob is created for each image, and the progress of the jobs is shown
+		until all of them finish. The images can be given on the command line, or derived
+		from the pod template of a workload with --from.`))
+
+	preheatExample = templates.Examples(`
+		# Pull two images on all the nodes
+		kubectl-kruise preheat nginx:1.21 busybox:1.33
+
+		# Pull an image on the nodes labeled role=web, three nodes at a time, giving up after 30 minutes
+		kubectl-kruise preheat nginx:1.21 --node-selector=role=web --parallel=3 --timeout=30m
+
+		# Pull all the images of the pod template of a cloneset
+		kubectl-kruise preheat --from=cloneset/nginx`)
+)
+
+// PreheatOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type PreheatOptions struct {
+	Images       []string
+	From         string
+	NodeSelector string
+	Parallel     int
+	Timeout      time.Duration
+
+	Namespace string
+	Builder   func() *resource.Builder
+	Client    client.Client
+	Reader    client.Reader
+
+	genericclioptions.IOStreams
+}
+
+// NewPreheatOptions returns an initialized PreheatOptions instance
+func NewPreheatOptions(streams genericclioptions.IOStreams) *PreheatOptions {
+	return &PreheatOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdPreheat returns a Command instance for 'preheat' sub command
+func NewCmdPreheat(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewPreheatOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "preheat (IMAGE... | --from=TYPE/NAME) [--node-selector=selector] [--parallel=N] [--timeout=duration]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Pre-pull images on nodes with ImagePullJobs"),
+		Long:                  preheatLong,
+		Example:               preheatExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.From, "from", o.From, "Pull the images of the pod template of this workload, e.g. cloneset/nginx.")
+	cmd.Flags().StringVar(&o.NodeSelector, "node-selector", o.NodeSelector, "Pull on the nodes matching this label selector only (e.g. role=web,zone in (a,b)). Defaults to all the nodes.")
+	cmd.Flags().IntVar(&o.Parallel, "parallel", o.Parallel, "The number of nodes to pull each image on at the same time. Zero means the default of the ImagePullJob.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The length of time to wait for the images to be pulled before giving up, zero means never. Any other values should contain a corresponding time unit (e.g. 1s, 2m, 3h).")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *PreheatOptions) Complete(f cmdutil.Factory, args []string) error {
+	o.Images = args
+
+	var err error
+	o.Namespace, _, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Builder = f.NewBuilder
+	return nil
+}
+
+// Validate makes sure provided values in PreheatOptions are valid
+func (o *PreheatOptions) Validate() error {
+	if len(o.Images) == 0 && len(o.From) == 0 {
+		return fmt.Errorf("at least one image or --from must be specified")
+	}
+	if len(o.Images) > 0 && len(o.From) > 0 {
+		return fmt.Errorf("images and --from cannot be used together")
+	}
+	if len(o.NodeSelector) > 0 {
+		if _, err := metav1.ParseToLabelSelector(o.NodeSelector); err != nil {
+			return fmt.Errorf("invalid --node-selector %q: %v", o.NodeSelector, err)
+		}
+	}
+	if o.Parallel < 0 {
+		return fmt.Errorf("--parallel must not be negative")
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative")
+	}
+	return nil
+}
+
+// Run performs the execution of 'preheat' sub command
+func (o *PreheatOptions) Run() error {
+	if o.Client == nil {
+		cl := util.BaseClient()
+		o.Client = cl.Client
+		o.Reader = cl.Reader
+	}
+
+	images := o.Images
+	var pullSecrets []string
+	if len(o.From) > 0 {
+		var err error
+		if images, pullSecrets, err = o.imagesFromWorkload(); err != nil {
+			return err
+		}
+	}
+
+	var selector *kruiseappsv1alpha1.ImagePullJobNodeSelector
+	if len(o.NodeSelector) > 0 {
+		labelSelector, err := metav1.ParseToLabelSelector(o.NodeSelector)
+		if err != nil {
+			return err
+		}
+		selector = &kruiseappsv1alpha1.ImagePullJobNodeSelector{LabelSelector: *labelSelector}
+	}
+	var parallelism *intstr.IntOrString
+	if o.Parallel > 0 {
+		value := intstr.FromInt(o.Parallel)
+		parallelism = &value
+	}
+
+	var jobs []*kruiseappsv1alpha1.ImagePullJob
+	var createErr error
+	for _, image := range images {
+		job := imagepulljob.NewJob(o.Namespace, image, selector, parallelism, o.Timeout)
+		job.Spec.PullSecrets = pullSecrets
+		if err := o.Client.Create(context.TODO(), job); err != nil {
+			createErr = fmt.Errorf("failed to create imagepulljob for %s: %v", image, err)
+			break
+		}
+		fmt.Fprintf(o.Out, "imagepulljob.apps.kruise.io/%s created for %s\n", job.Name, image)
+		jobs = append(jobs, job)
+	}
+	if len(jobs) == 0 {
+		return createErr
+	}
+	if createErr != nil {
+		// the jobs already created keep pulling anyway, so wait for them rather than leaving them unreported
+		fmt.Fprintf(o.ErrOut, "%v, waiting for the %d imagepulljobs already created\n", createErr, len(jobs))
+	}
+
+	return utilerrors.NewAggregate([]error{createErr, o.waitForJobs(jobs)})
+}
+
+// imagesFromWorkload returns the images and image pull secrets of the pod template of the workload given by --from.
+func (o *PreheatOptions) imagesFromWorkload() ([]string, []string, error) {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		ResourceTypeOrNameArgs(true, o.From).
+		SingleResourceType().
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(infos) != 1 {
+		return nil, nil, fmt.Errorf("--from must select exactly one workload, got %d", len(infos))
+	}
+
+	var images, pullSecrets []string
+	ok, err := internalpolymorphichelpers.UpdatePodSpecForObjectFn(infos[0].Object, func(spec *corev1.PodSpec) error {
+		images = imagepulljob.ImagesForPodSpec(spec)
+		for _, secret := range spec.ImagePullSecrets {
+			pullSecrets = append(pullSecrets, secret.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("%s %q has no pod template", infos[0].Mapping.Resource.Resource, infos[0].Name)
+	}
+	if len(images) == 0 {
+		return nil, nil, fmt.Errorf("%s %q has no images", infos[0].Mapping.Resource.Resource, infos[0].Name)
+	}
+	return images, pullSecrets, nil
+}
+
+// waitForJobs shows the progress of the jobs until all of them are completed, or the timeout expires.
+func (o *PreheatOptions) waitForJobs(jobs []*kruiseappsv1alpha1.ImagePullJob) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	if o.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), o.Timeout)
+	}
+	defer cancel()
+
+	table := util.NewLiveTable(o.Out, "IMAGE", "JOB", "DESIRED", "SUCCEEDED", "FAILED", "ACTIVE", "STATUS")
+	intr := interrupt.New(nil, cancel)
+	return intr.Run(func() error {
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			errs = make([]error, len(jobs))
+		)
+		table.Render(progressRows(jobs))
+		for i := range jobs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				job, err := imagepulljob.Wait(ctx, o.Reader, jobs[i], func(job *kruiseappsv1alpha1.ImagePullJob) {
+					mu.Lock()
+					defer mu.Unlock()
+					jobs[i] = job
+					table.Render(progressRows(jobs))
+				})
+				mu.Lock()
+				defer mu.Unlock()
+				jobs[i] = job
+				errs[i] = err
+			}(i)
+		}
+		wg.Wait()
+
+		for i, job := range jobs {
+			if errs[i] != nil {
+				continue
+			}
+			if job.Status.Failed > 0 {
+				errs[i] = fmt.Errorf("failed to pull %s on %d of %d nodes: %s",
+					job.Spec.Image, job.Status.Failed, job.Status.Desired, strings.Join(job.Status.FailedNodes, ", "))
+			} else if job.Status.Succeeded < job.Status.Desired {
+				errs[i] = fmt.Errorf("pulled %s on %d of %d nodes: %s",
+					job.Spec.Image, job.Status.Succeeded, job.Status.Desired, job.Status.Message)
+			}
+		}
+		return utilerrors.NewAggregate(errs)
+	})
+}
+
+// progressRows returns the rows of the progress of the jobs in a table.
+func progressRows(jobs []*kruiseappsv1alpha1.ImagePullJob) []util.TableRow {
+	rows := make([]util.TableRow, 0, len(jobs))
+	for _, job := range jobs {
+		rows = append(rows, util.TableRow{Key: job.Name, Cells: []string{job.Spec.Image, job.Name,
+			fmt.Sprint(job.Status.Desired), fmt.Sprint(job.Status.Succeeded),
+			fmt.Sprint(job.Status.Failed), fmt.Sprint(job.Status.Active), jobStatus(job)}})
+	}
+	return rows
+}
+
+func jobStatus(job *kruiseappsv1alpha1.ImagePullJob) string {
+	switch {
+	case !imagepulljob.IsCompleted(job):
+		return "Pulling"
+	case job.Status.Failed > 0 || job.Status.Succeeded < job.Status.Desired:
+		return "Failed"
+	default:
+		return "Done"
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preheat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// jobClient names the created ImagePullJobs, completes them at once, and fails to create the job of failImage.
+type jobClient struct {
+	client.Client
+	failImage string
+	created   int
+}
+
+func (c *jobClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	job := obj.(*kruiseappsv1alpha1.ImagePullJob)
+	if job.Spec.Image == c.failImage {
+		return fmt.Errorf("forbidden")
+	}
+	c.created++
+	job.Name = fmt.Sprintf("%s%d", job.GenerateName, c.created)
+	now := metav1.Now()
+	job.Status = kruiseappsv1alpha1.ImagePullJobStatus{Desired: 2, Succeeded: 2, CompletionTime: &now}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		o       *PreheatOptions
+		wantErr bool
+	}{
+		{name: "images", o: &PreheatOptions{Images: []string{"nginx:1.21"}, NodeSelector: "role=web,zone in (a,b)", Parallel: 3}},
+		{name: "from", o: &PreheatOptions{From: "cloneset/nginx", Timeout: time.Minute}},
+		{name: "nothing to pull", o: &PreheatOptions{}, wantErr: true},
+		{name: "images with from", o: &PreheatOptions{Images: []string{"nginx:1.21"}, From: "cloneset/nginx"}, wantErr: true},
+		{name: "invalid node selector", o: &PreheatOptions{Images: []string{"nginx:1.21"}, NodeSelector: "role in web"}, wantErr: true},
+		{name: "negative parallel", o: &PreheatOptions{Images: []string{"nginx:1.21"}, Parallel: -1}, wantErr: true},
+		{name: "negative timeout", o: &PreheatOptions{Images: []string{"nginx:1.21"}, Timeout: -time.Second}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.o.Validate(); (err != nil) != test.wantErr {
+				t.Errorf("expected error %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProgressRows(t *testing.T) {
+	now := metav1.Now()
+	jobs := []*kruiseappsv1alpha1.ImagePullJob{
+		{ObjectMeta: metav1.ObjectMeta{Name: "preheat-a"}, Spec: kruiseappsv1alpha1.ImagePullJobSpec{Image: "nginx:1.21"},
+			Status: kruiseappsv1alpha1.ImagePullJobStatus{Desired: 3, Succeeded: 1, Active: 2}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "preheat-b"}, Spec: kruiseappsv1alpha1.ImagePullJobSpec{Image: "busybox:1.33"},
+			Status: kruiseappsv1alpha1.ImagePullJobStatus{Desired: 3, Succeeded: 2, Failed: 1, CompletionTime: &now}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "preheat-c"}, Spec: kruiseappsv1alpha1.ImagePullJobSpec{Image: "redis:6"},
+			Status: kruiseappsv1alpha1.ImagePullJobStatus{Desired: 3, Succeeded: 3, CompletionTime: &now}},
+	}
+
+	rows := progressRows(jobs)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "preheat-a", rows[0].Key)
+	assert.Equal(t, []string{"nginx:1.21", "preheat-a", "3", "1", "0", "2", "Pulling"}, rows[0].Cells)
+	assert.Equal(t, "Failed", rows[1].Cells[6])
+	assert.Equal(t, "Done", rows[2].Cells[6])
+}
+
+func TestRun(t *testing.T) {
+	var out, errOut *bytes.Buffer
+	newOptions := func(failImage string) (*PreheatOptions, *jobClient) {
+		var streams genericclioptions.IOStreams
+		streams, _, out, errOut = genericclioptions.NewTestIOStreams()
+		c := &jobClient{Client: fake.NewFakeClientWithScheme(internalapi.GetScheme()), failImage: failImage}
+		return &PreheatOptions{
+			Images:    []string{"nginx:1.21", "busybox:1.33", "redis:6"},
+			Namespace: "default",
+			Client:    c,
+			Reader:    c,
+			IOStreams: streams,
+		}, c
+	}
+
+	o, c := newOptions("")
+	assert.NoError(t, o.Run())
+	assert.Equal(t, 3, c.created)
+
+	// the jobs created before the failure are still waited for
+	o, c = newOptions("busybox:1.33")
+	err := o.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create imagepulljob for busybox:1.33")
+	assert.Equal(t, 1, c.created)
+	assert.Contains(t, out.String(), "preheat-1 created for nginx:1.21")
+	assert.Contains(t, errOut.String(), "waiting for the 1 imagepulljobs already created")
+
+	o, c = newOptions("nginx:1.21")
+	assert.Error(t, o.Run())
+	assert.Equal(t, 0, c.created)
+}
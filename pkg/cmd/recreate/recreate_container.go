@@ -0,0 +1,386 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recreate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/containerrecreate"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/interrupt"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	recreateContainerLong = templates.LongDesc(i18n.T(`
+		Recreate containers of pods in place with ContainerRecreateRequests.
+
+		A ContainerRecreateRequest is created for the pod, which stops the given containers,
+		or all the containers of the pod if none is given, and lets the kubelet start them again
+		without recreating the pod. Given a workload, the containers of its pods are recreated
+		at most --max-concurrency pods at a time, so the results are always waited for.
+
+		With --dry-run, the requests are only printed for each pod without being waited for.`))
+
+	recreateContainerExample = templates.Examples(`
+		# Recreate the app container of a pod
+		kubectl-kruise recreate container foo-abcde -c app
+
+		# Recreate the sidecar and app containers of a pod one after another, and wait for the result of each container
+		kubectl-kruise recreate container foo-abcde -c sidecar -c app --order --wait
+
+		# Recreate all the containers of a pod without waiting for them to stop gracefully
+		kubectl-kruise recreate container foo-abcde --force --wait
+
+		# Recreate the app container of the pods of a cloneset labeled zone=a, two pods at a time
+		kubectl-kruise recreate container cloneset/foo -c app -l zone=a --max-concurrency=2
+
+		# Print the requests to recreate the containers of the pods of a cloneset without creating them
+		kubectl-kruise recreate container cloneset/foo --dry-run=server`)
+)
+
+// RecreateContainerOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type RecreateContainerOptions struct {
+	Target         string
+	Containers     []string
+	Order          bool
+	Force          bool
+	Wait           bool
+	Timeout        time.Duration
+	Selector       string
+	MaxConcurrency int
+
+	DryRunStrategy cmdutil.DryRunStrategy
+	DryRunVerifier *resource.DryRunVerifier
+
+	Namespace string
+	Builder   func() *resource.Builder
+	Client    client.Client
+	Reader    client.Reader
+
+	// maxConcurrencySet is whether --max-concurrency is given, which pods can't be recreated with
+	maxConcurrencySet bool
+
+	genericclioptions.IOStreams
+}
+
+// NewRecreateContainerOptions returns an initialized RecreateContainerOptions instance
+func NewRecreateContainerOptions(streams genericclioptions.IOStreams) *RecreateContainerOptions {
+	return &RecreateContainerOptions{
+		MaxConcurrency: 1,
+		IOStreams:      streams,
+	}
+}
+
+// NewCmdRecreateContainer returns a Command instance for 'recreate container' sub command
+func NewCmdRecreateContainer(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRecreateContainerOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "container (POD | TYPE/NAME) [-c CONTAINER]... [--order] [--force] [--wait] [-l selector] [--max-concurrency=N] [--dry-run=server|client|none]",
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"containers"},
+		Short:                 i18n.T("Recreate containers of pods in place"),
+		Long:                  recreateContainerLong,
+		Example:               recreateContainerExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmdutil.AddDryRunFlag(cmd)
+	cmd.Flags().StringSliceVarP(&o.Containers, "container", "c", o.Containers, "The containers to recreate. Defaults to all the containers of the pods.")
+	cmd.Flags().BoolVar(&o.Order, "order", o.Order, "If true, recreate the containers one after another in the order of the pod spec, each once the previous one is ready.")
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "If true, kill the containers immediately instead of waiting for them to stop gracefully.")
+	cmd.Flags().BoolVar(&o.Wait, "wait", o.Wait, "If true, wait for the containers to be recreated and print the result of each container. Always true for workloads.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The length of time to wait with --wait, zero means never. Any other values should contain a corresponding time unit (e.g. 1s, 2m, 3h).")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter the pods of the workload on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().IntVar(&o.MaxConcurrency, "max-concurrency", o.MaxConcurrency, "The number of pods of the workload to recreate containers of at the same time. No more pods are started once any of them fails.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *RecreateContainerOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmdutil.UsageErrorf(cmd, "exactly one POD or TYPE/NAME is required")
+	}
+	o.Target = args[0]
+
+	var err error
+	o.Namespace, _, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Builder = f.NewBuilder
+	o.maxConcurrencySet = cmd.Flags().Changed("max-concurrency")
+
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := f.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+	return nil
+}
+
+// Validate makes sure provided values in RecreateContainerOptions are valid
+func (o *RecreateContainerOptions) Validate() error {
+	if len(o.Selector) > 0 {
+		if _, err := labels.Parse(o.Selector); err != nil {
+			return fmt.Errorf("invalid --selector %q: %v", o.Selector, err)
+		}
+	}
+	if o.MaxConcurrency < 1 {
+		return fmt.Errorf("--max-concurrency must be positive")
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative")
+	}
+	if o.Wait && o.DryRunStrategy != cmdutil.DryRunNone {
+		return fmt.Errorf("cannot specify --wait and --dry-run")
+	}
+	return nil
+}
+
+// Run performs the execution of 'recreate container' sub command
+func (o *RecreateContainerOptions) Run() error {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		ResourceNames("pods", o.Target).
+		Latest().
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return err
+	}
+	if len(infos) != 1 {
+		return fmt.Errorf("expected exactly one pod or workload, got %d", len(infos))
+	}
+
+	if o.Client == nil {
+		cl := util.BaseClient()
+		o.Client = cl.Client
+		o.Reader = cl.Reader
+	}
+	return o.recreateContainers(infos[0])
+}
+
+// recreateContainers recreates the containers of the pod in info, or of the pods of the workload in info.
+// The pods of a workload are always waited for to keep at most MaxConcurrency pods recreating at a time.
+func (o *RecreateContainerOptions) recreateContainers(info *resource.Info) error {
+	_, isPod := info.Object.(*corev1.Pod)
+	if isPod && len(o.Selector) > 0 {
+		return fmt.Errorf("--selector can only be used with workloads, not pod %s", info.Name)
+	}
+	if isPod && o.maxConcurrencySet {
+		return fmt.Errorf("--max-concurrency can only be used with workloads, not pod %s", info.Name)
+	}
+
+	pods, err := o.selectPods(info)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no pods found in %s %s", info.Mapping.Resource.Resource, info.Name)
+	}
+
+	if o.DryRunStrategy != cmdutil.DryRunNone || isPod && !o.Wait {
+		return o.createRequests(pods)
+	}
+	return o.recreateAndWait(pods)
+}
+
+// createRequests creates the requests for the pods without waiting for them, or only prints them with --dry-run.
+func (o *RecreateContainerOptions) createRequests(pods []*corev1.Pod) error {
+	var createOptions []client.CreateOption
+	suffix := ""
+	switch o.DryRunStrategy {
+	case cmdutil.DryRunClient:
+		suffix = " (dry run)"
+	case cmdutil.DryRunServer:
+		if err := o.DryRunVerifier.HasSupport(kruiseappsv1alpha1.SchemeGroupVersion.WithKind("ContainerRecreateRequest")); err != nil {
+			return err
+		}
+		createOptions = append(createOptions, client.DryRunAll)
+		suffix = " (server dry run)"
+	}
+
+	for _, pod := range pods {
+		crr, err := containerrecreate.NewRequest(pod, o.Containers, o.strategy())
+		if err == nil && o.DryRunStrategy != cmdutil.DryRunClient {
+			err = o.Client.Create(context.TODO(), crr, createOptions...)
+		}
+		if err != nil {
+			return fmt.Errorf("pod %s: %v", pod.Name, err)
+		}
+		// the name is generated by the server, so it is unknown with client dry-run
+		name := crr.Name
+		if len(name) == 0 {
+			name = crr.GenerateName
+		}
+		fmt.Fprintf(o.Out, "containerrecreaterequest.apps.kruise.io/%s created for pod %s%s\n", name, pod.Name, suffix)
+	}
+	return nil
+}
+
+// selectPods returns the pod in info, or the pods of the workload in info matching the selector.
+func (o *RecreateContainerOptions) selectPods(info *resource.Info) ([]*corev1.Pod, error) {
+	if pod, ok := info.Object.(*corev1.Pod); ok {
+		return []*corev1.Pod{pod}, nil
+	}
+
+	pods, err := internalpolymorphichelpers.ControlledPods(o.Reader, info.Object)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.Selector) == 0 {
+		return pods, nil
+	}
+	selector, err := labels.Parse(o.Selector)
+	if err != nil {
+		return nil, err
+	}
+	var selected []*corev1.Pod
+	for _, pod := range pods {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			selected = append(selected, pod)
+		}
+	}
+	return selected, nil
+}
+
+// strategy returns the strategy of the requests given by the flags.
+func (o *RecreateContainerOptions) strategy() *kruiseappsv1alpha1.ContainerRecreateRequestStrategy {
+	strategy := &kruiseappsv1alpha1.ContainerRecreateRequestStrategy{
+		FailurePolicy:   kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyFail,
+		OrderedRecreate: o.Order,
+	}
+	if o.Force {
+		gracePeriod := int64(0)
+		strategy.TerminationGracePeriodSeconds = &gracePeriod
+	}
+	return strategy
+}
+
+// recreateAndWait recreates the containers of the pods, at most MaxConcurrency pods at a time,
+// printing the phase changes of each request and the result of each container in the end.
+// No more pods are started once any of them fails.
+func (o *RecreateContainerOptions) recreateAndWait(pods []*corev1.Pod) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	if o.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), o.Timeout)
+	}
+	defer cancel()
+
+	intr := interrupt.New(nil, cancel)
+	return intr.Run(func() error {
+		created := sets.NewString()
+		results, err := containerrecreate.RecreatePods(ctx, o.Client, o.Reader, pods, o.Containers, o.strategy(), o.MaxConcurrency,
+			func(pod *corev1.Pod, crr *kruiseappsv1alpha1.ContainerRecreateRequest) {
+				if !created.Has(pod.Name) {
+					created.Insert(pod.Name)
+					fmt.Fprintf(o.Out, "containerrecreaterequest.apps.kruise.io/%s created for pod %s\n", crr.Name, pod.Name)
+				}
+				fmt.Fprintf(o.Out, "pod/%s: %s\n", pod.Name, describePhases(crr))
+			})
+		o.printResults(results)
+		return err
+	})
+}
+
+// printResults prints the result of each container of the requests, skipping the requests never created.
+func (o *RecreateContainerOptions) printResults(results []*kruiseappsv1alpha1.ContainerRecreateRequest) {
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	fmt.Fprintln(w, "POD\tCONTAINER\tPHASE\tMESSAGE")
+	for _, crr := range results {
+		if crr == nil {
+			continue
+		}
+		for _, result := range containerResults(crr) {
+			message := result.Message
+			if len(message) == 0 {
+				message = "<none>"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", crr.Spec.PodName, result.Name, result.Phase, message)
+		}
+	}
+}
+
+// describePhases describes the phase of the request and of each of its containers, e.g. "Recreating (app: Succeeded, sidecar: Recreating)".
+func describePhases(crr *kruiseappsv1alpha1.ContainerRecreateRequest) string {
+	phase := string(crr.Status.Phase)
+	if len(phase) == 0 {
+		phase = string(kruiseappsv1alpha1.ContainerRecreateRequestPending)
+	}
+	var containers []string
+	for _, result := range containerResults(crr) {
+		containers = append(containers, fmt.Sprintf("%s: %s", result.Name, result.Phase))
+	}
+	return fmt.Sprintf("%s (%s)", phase, strings.Join(containers, ", "))
+}
+
+// containerResults returns the state of each container of the request in the order of its spec.
+// The containers without any state yet are Pending.
+func containerResults(crr *kruiseappsv1alpha1.ContainerRecreateRequest) []kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState {
+	states := make(map[string]kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState, len(crr.Status.ContainerRecreateStates))
+	for _, state := range crr.Status.ContainerRecreateStates {
+		states[state.Name] = state
+	}
+	results := make([]kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState, 0, len(crr.Spec.Containers))
+	for _, c := range crr.Spec.Containers {
+		state, ok := states[c.Name]
+		if !ok {
+			state = kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState{
+				Name:  c.Name,
+				Phase: kruiseappsv1alpha1.ContainerRecreateRequestPending,
+			}
+		}
+		results = append(results, state)
+	}
+	return results
+}
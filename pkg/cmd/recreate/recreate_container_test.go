@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recreate
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// crrClient names the created ContainerRecreateRequests after their pods, and with complete set,
+// completes them at once, failing the containers of failPod.
+type crrClient struct {
+	client.Client
+	complete bool
+	failPod  string
+
+	mu      sync.Mutex
+	created []string
+}
+
+func (c *crrClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	crr := obj.(*kruiseappsv1alpha1.ContainerRecreateRequest)
+	crr.Name = crr.GenerateName + "crr"
+	if c.complete {
+		phase := kruiseappsv1alpha1.ContainerRecreateRequestSucceeded
+		if crr.Spec.PodName == c.failPod {
+			phase = kruiseappsv1alpha1.ContainerRecreateRequestFailed
+		}
+		crr.Status.Phase = kruiseappsv1alpha1.ContainerRecreateRequestCompleted
+		for _, container := range crr.Spec.Containers {
+			crr.Status.ContainerRecreateStates = append(crr.Status.ContainerRecreateStates,
+				kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState{Name: container.Name, Phase: phase})
+		}
+	}
+	c.mu.Lock()
+	c.created = append(c.created, crr.Spec.PodName)
+	c.mu.Unlock()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestDescribePhases(t *testing.T) {
+	crr := &kruiseappsv1alpha1.ContainerRecreateRequest{
+		Spec: kruiseappsv1alpha1.ContainerRecreateRequestSpec{
+			Containers: []kruiseappsv1alpha1.ContainerRecreateRequestContainer{{Name: "sidecar"}, {Name: "app"}},
+		},
+	}
+	assert.Equal(t, "Pending (sidecar: Pending, app: Pending)", describePhases(crr))
+
+	crr.Status = kruiseappsv1alpha1.ContainerRecreateRequestStatus{
+		Phase: kruiseappsv1alpha1.ContainerRecreateRequestRecreating,
+		ContainerRecreateStates: []kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState{
+			{Name: "app", Phase: kruiseappsv1alpha1.ContainerRecreateRequestRecreating},
+			{Name: "sidecar", Phase: kruiseappsv1alpha1.ContainerRecreateRequestSucceeded},
+		},
+	}
+	assert.Equal(t, "Recreating (sidecar: Succeeded, app: Recreating)", describePhases(crr))
+}
+
+func TestRecreateContainers(t *testing.T) {
+	isController := true
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "web-uid"},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+	}
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "web-abc", UID: "web-abc-uid",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", UID: "web-uid", Controller: &isController}},
+	}}
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "default",
+				Name:            name,
+				Labels:          map[string]string{"app": "web", "pod": name},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-abc", UID: "web-abc-uid", Controller: &isController}},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		}
+	}
+	deploymentInfo := &resource.Info{
+		Name:    "web",
+		Object:  deployment,
+		Mapping: &meta.RESTMapping{Resource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}},
+	}
+
+	tests := []struct {
+		name        string
+		info        *resource.Info
+		objs        []runtime.Object
+		wait        bool
+		complete    bool
+		failPod     string
+		selector    string
+		concurrency bool
+		dryRun      cmdutil.DryRunStrategy
+		expectErr   string
+		expectPods  []string
+		expectOut   []string
+		unexpectOut []string
+	}{
+		{
+			name:        "pod without wait",
+			info:        &resource.Info{Name: "web-a", Object: newPod("web-a")},
+			expectPods:  []string{"web-a"},
+			expectOut:   []string{"containerrecreaterequest.apps.kruise.io/web-a-crr created for pod web-a"},
+			unexpectOut: []string{"POD"},
+		},
+		{
+			name:       "pod with wait",
+			info:       &resource.Info{Name: "web-a", Object: newPod("web-a")},
+			wait:       true,
+			complete:   true,
+			expectPods: []string{"web-a"},
+			expectOut:  []string{"pod/web-a: Completed (app: Succeeded)", "web-a   app         Succeeded"},
+		},
+		{
+			name:       "workload is waited for without wait",
+			info:       deploymentInfo,
+			objs:       []runtime.Object{deployment, replicaSet, newPod("web-a"), newPod("web-b"), newPod("web-c")},
+			complete:   true,
+			failPod:    "web-b",
+			expectErr:  "[pod web-b: container app Failed, 1 pods are skipped]",
+			expectPods: []string{"web-a", "web-b"},
+			expectOut:  []string{"web-a   app         Succeeded", "web-b   app         Failed"},
+		},
+		{
+			name:       "workload with selector",
+			info:       deploymentInfo,
+			objs:       []runtime.Object{deployment, replicaSet, newPod("web-a"), newPod("web-b")},
+			complete:   true,
+			selector:   "pod!=web-a",
+			expectPods: []string{"web-b"},
+			expectOut:  []string{"web-b   app         Succeeded"},
+		},
+		{
+			name:        "pod with client dry run",
+			info:        &resource.Info{Name: "web-a", Object: newPod("web-a")},
+			dryRun:      cmdutil.DryRunClient,
+			expectOut:   []string{"containerrecreaterequest.apps.kruise.io/web-a- created for pod web-a (dry run)"},
+			unexpectOut: []string{"POD"},
+		},
+		{
+			name:        "workload with client dry run is not waited for",
+			info:        deploymentInfo,
+			objs:        []runtime.Object{deployment, replicaSet, newPod("web-a"), newPod("web-b")},
+			dryRun:      cmdutil.DryRunClient,
+			expectOut:   []string{"created for pod web-a (dry run)", "created for pod web-b (dry run)"},
+			unexpectOut: []string{"POD"},
+		},
+		{
+			name:      "pod with selector",
+			info:      &resource.Info{Name: "web-a", Object: newPod("web-a")},
+			selector:  "pod=web-a",
+			expectErr: "--selector can only be used with workloads, not pod web-a",
+		},
+		{
+			name:        "pod with max concurrency",
+			info:        &resource.Info{Name: "web-a", Object: newPod("web-a")},
+			concurrency: true,
+			expectErr:   "--max-concurrency can only be used with workloads, not pod web-a",
+		},
+		{
+			name:      "workload without pods",
+			info:      deploymentInfo,
+			objs:      []runtime.Object{deployment, replicaSet},
+			expectErr: "no pods found in deployments web",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			o := NewRecreateContainerOptions(streams)
+			o.Wait, o.Selector, o.maxConcurrencySet, o.DryRunStrategy = test.wait, test.selector, test.concurrency, test.dryRun
+			c := &crrClient{Client: fake.NewFakeClientWithScheme(internalapi.GetScheme(), test.objs...), complete: test.complete, failPod: test.failPod}
+			o.Client, o.Reader = c, c
+
+			err := o.recreateContainers(test.info)
+			if len(test.expectErr) > 0 {
+				assert.EqualError(t, err, test.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectPods, c.created)
+			for _, expected := range test.expectOut {
+				assert.Contains(t, out.String(), expected)
+			}
+			for _, unexpected := range test.unexpectOut {
+				assert.False(t, strings.Contains(out.String(), unexpected), unexpected)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/dynamic"
+	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/kubectl/pkg/cmd/set"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/interrupt"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// ApproveOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type ApproveOptions struct {
+	PrintFlags *genericclioptions.PrintFlags
+	ToPrinter  func(string) (printers.ResourcePrinter, error)
+
+	Batch   string
+	To      int32
+	All     bool
+	Wait    bool
+	Timeout time.Duration
+
+	Step           internalpolymorphichelpers.ApproveStep
+	Approver       internalpolymorphichelpers.ObjectApproverFunc
+	StatusViewerFn func(*meta.RESTMapping) (internalpolymorphichelpers.StatusViewer, error)
+	DynamicClient  dynamic.Interface
+
+	Builder          func() *resource.Builder
+	Namespace        string
+	EnforceNamespace bool
+	Resources        []string
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+var (
+	approveLong = templates.LongDesc(`
+		Approve the next batch of a partitioned rollout.
+
+		The partition of the resource is lowered by one step, so that more pods are updated
+		to the latest revision. By default the command waits until the updated pods are ready
+		and prints the new rollout status.
+		Currently clonesets and advanced statefulsets support being approved.`)
+
+	approveExample = templates.Examples(`
+		# Update another 20% of the pods of the cloneset
+		kubectl-kruise rollout approve cloneset/nginx --batch=20%
+
+		# Lower the partition of the cloneset to 5
+		kubectl-kruise rollout approve cloneset/nginx --to=5
+
+		# Update all the remaining pods of the advanced statefulset without waiting
+		kubectl-kruise rollout approve statefulset.apps.kruise.io/nginx --all --wait=false`)
+)
+
+// NewCmdRolloutApprove returns a Command instance for 'rollout approve' sub command
+func NewCmdRolloutApprove(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := &ApproveOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("approved").WithTypeSetter(internalapi.GetScheme()),
+		To:         -1,
+		Wait:       true,
+		IOStreams:  streams,
+	}
+
+	validArgs := []string{"cloneset", "statefulset"}
+
+	cmd := &cobra.Command{
+		Use:                   "approve RESOURCE (--batch=N|--to=N|--all)",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Approve the next batch of a partitioned rollout"),
+		Long:                  approveLong,
+		Example:               approveExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunApprove())
+		},
+		ValidArgs: validArgs,
+	}
+
+	o.PrintFlags.AddFlags(cmd)
+
+	usage := "identifying the resource to get from a server."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringVar(&o.Batch, "batch", o.Batch, "The number or percentage of replicas to lower the partition by, e.g. 2 or 20%.")
+	cmd.Flags().Int32Var(&o.To, "to", o.To, "The partition to lower to.")
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Lower the partition to 0 to update all the remaining pods.")
+	cmd.Flags().BoolVarP(&o.Wait, "wait", "w", o.Wait, "Wait for the updated pods to be ready.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The length of time to wait for the updated pods, zero means never. Any other values should contain a corresponding time unit (e.g. 1s, 2m, 3h).")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *ApproveOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	o.Approver = internalpolymorphichelpers.ObjectApproverFn
+	o.StatusViewerFn = internalpolymorphichelpers.StatusViewerFn
+
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	o.Resources = args
+	o.Builder = f.NewBuilder
+
+	o.ToPrinter = func(operation string) (printers.ResourcePrinter, error) {
+		o.PrintFlags.NamePrintFlags.Operation = operation
+		return o.PrintFlags.ToPrinter()
+	}
+
+	if len(o.Batch) > 0 {
+		batch := intstr.Parse(o.Batch)
+		o.Step.Batch = &batch
+	}
+	if o.To >= 0 {
+		o.Step.To = &o.To
+	}
+	o.Step.All = o.All
+
+	if o.Wait {
+		clientConfig, err := f.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		o.DynamicClient, err = dynamic.NewForConfig(clientConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *ApproveOptions) Validate() error {
+	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
+
+	steps := 0
+	if o.Step.Batch != nil {
+		if _, err := intstr.GetValueFromIntOrPercent(o.Step.Batch, 100, true); err != nil {
+			return fmt.Errorf("invalid --batch %q: %v", o.Batch, err)
+		}
+		steps++
+	}
+	if o.Step.To != nil {
+		steps++
+	}
+	if o.Step.All {
+		steps++
+	}
+	if steps != 1 {
+		return fmt.Errorf("exactly one of --batch, --to and --all must be specified")
+	}
+	return nil
+}
+
+// RunApprove performs the execution of 'rollout approve' sub command
+func (o *ApproveOptions) RunApprove() error {
+	r := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		ResourceTypeOrNameArgs(true, o.Resources...).
+		ContinueOnError().
+		Latest().
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	var allErrs []error
+	infos, err := r.Infos()
+	if err != nil {
+		allErrs = append(allErrs, err)
+	}
+
+	approver := func(obj runtime.Object) ([]byte, error) {
+		return o.Approver(obj, o.Step)
+	}
+
+	var approved []*resource.Info
+	for _, patch := range set.CalculatePatches(infos, scheme.DefaultJSONEncoder(), approver) {
+		info := patch.Info
+
+		if patch.Err != nil {
+			resourceString := info.Mapping.Resource.Resource
+			if len(info.Mapping.Resource.Group) > 0 {
+				resourceString = resourceString + "." + info.Mapping.Resource.Group
+			}
+			allErrs = append(allErrs, fmt.Errorf("error: %s %q %v", resourceString, info.Name, patch.Err))
+			continue
+		}
+
+		obj, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, patch.Patch, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch: %v", err))
+			continue
+		}
+
+		info.Refresh(obj, true)
+		printer, err := o.ToPrinter("approved")
+		if err != nil {
+			allErrs = append(allErrs, err)
+			continue
+		}
+		if err = printer.PrintObj(info.Object, o.Out); err != nil {
+			allErrs = append(allErrs, err)
+		}
+		approved = append(approved, info)
+	}
+
+	if o.Wait {
+		for _, info := range approved {
+			if err := o.waitForApproved(info); err != nil {
+				allErrs = append(allErrs, fmt.Errorf("failed waiting for %s %q: %v", info.Mapping.Resource.Resource, info.Name, err))
+			}
+		}
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// waitForApproved waits until the updated pods of the approved object are ready.
+func (o *ApproveOptions) waitForApproved(info *resource.Info) error {
+	statusViewer, err := o.StatusViewerFn(info.ResourceMapping())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), o.Timeout)
+	intr := interrupt.New(nil, cancel)
+	return intr.Run(func() error {
+		return watchRolloutStatus(ctx, o.DynamicClient, info, statusViewer, 0, true, o.Out)
+	})
+}
@@ -19,11 +19,15 @@ package rollout
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
@@ -44,8 +48,24 @@ var (
 		# View the rollout history of a advanced statefulset
 		kubectl-kruise rollout history asts/abc
 
+		# View the rollout history of an advanced daemonset and a uniteddeployment
+		kubectl-kruise rollout history daemonset.apps.kruise.io/abc
+		kubectl-kruise rollout history uniteddeployment/abc
+
 		# View the details of daemonset revision 3
-		kubectl-kruise rollout history daemonset/abc --revision=3`)
+		kubectl-kruise rollout history daemonset/abc --revision=3
+
+		# List the revisions of a cloneset with their images in JSON
+		kubectl-kruise rollout history cloneset/abc -o json
+
+		# Print the name of the ControllerRevision of cloneset revision 3
+		kubectl-kruise rollout history cloneset/abc --revision=3 -o jsonpath='{.items[0].name}'
+
+		# Compare the pod templates of cloneset revision 3 and 5
+		kubectl-kruise rollout history cloneset/abc --revision=3 --diff-to=5
+
+		# Compare the pod template of cloneset revision 3 with the current one
+		kubectl-kruise rollout history cloneset/abc --revision=3 --diff-to=current`)
 )
 
 // RolloutHistoryOptions holds the options for 'rollout history' sub command
@@ -54,6 +74,9 @@ type RolloutHistoryOptions struct {
 	ToPrinter  func(string) (printers.ResourcePrinter, error)
 
 	Revision int64
+	DiffTo   string
+
+	diffToRevision int64
 
 	Builder          func() *resource.Builder
 	Resources        []string
@@ -79,7 +102,7 @@ func NewRolloutHistoryOptions(streams genericclioptions.IOStreams) *RolloutHisto
 func NewCmdRolloutHistory(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
 	o := NewRolloutHistoryOptions(streams)
 
-	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset"}
+	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset", "uniteddeployment"}
 
 	cmd := &cobra.Command{
 		Use:                   "history (TYPE NAME | TYPE/NAME) [flags]",
@@ -96,6 +119,7 @@ func NewCmdRolloutHistory(f cmdutil.Factory, streams genericclioptions.IOStreams
 	}
 
 	cmd.Flags().Int64Var(&o.Revision, "revision", o.Revision, "See the details, including podTemplate of the revision specified")
+	cmd.Flags().StringVar(&o.DiffTo, "diff-to", o.DiffTo, "Show the diff between the podTemplate of the revision specified and this revision, or 'current' for the current podTemplate")
 
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
@@ -134,6 +158,18 @@ func (o *RolloutHistoryOptions) Validate() error {
 	if o.Revision < 0 {
 		return fmt.Errorf("revision must be a positive integer: %v", o.Revision)
 	}
+	if len(o.DiffTo) > 0 {
+		if o.Revision == 0 {
+			return fmt.Errorf("--revision must be specified with --diff-to")
+		}
+		if o.DiffTo != "current" {
+			revision, err := strconv.ParseInt(o.DiffTo, 10, 64)
+			if err != nil || revision <= 0 {
+				return fmt.Errorf("--diff-to must be a positive integer or 'current': %v", o.DiffTo)
+			}
+			o.diffToRevision = revision
+		}
+	}
 
 	return nil
 }
@@ -164,6 +200,20 @@ func (o *RolloutHistoryOptions) Run() error {
 		if err != nil {
 			return err
 		}
+
+		if len(o.DiffTo) > 0 {
+			diff, err := internalpolymorphichelpers.DiffHistory(historyViewer, info.Namespace, info.Name, o.Revision, o.diffToRevision)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprint(o.Out, diff)
+			return err
+		}
+
+		if o.isStructuredOutput() {
+			return o.printRevisionHistory(historyViewer, info)
+		}
+
 		historyInfo, err := historyViewer.ViewHistory(info.Namespace, info.Name, o.Revision)
 		if err != nil {
 			return err
@@ -182,3 +232,52 @@ func (o *RolloutHistoryOptions) Run() error {
 		return printer.PrintObj(info.Object, o.Out)
 	})
 }
+
+// isStructuredOutput returns true if the history should be printed as an object, e.g. -o json.
+func (o *RolloutHistoryOptions) isStructuredOutput() bool {
+	return o.PrintFlags.OutputFormat != nil && len(*o.PrintFlags.OutputFormat) > 0 && *o.PrintFlags.OutputFormat != "name"
+}
+
+// printRevisionHistory prints the revisions of the resource as a List, which includes the pod template
+// only if a revision is specified.
+func (o *RolloutHistoryOptions) printRevisionHistory(historyViewer internalpolymorphichelpers.HistoryViewer, info *resource.Info) error {
+	revisions, _, err := historyViewer.GetHistory(info.Namespace, info.Name)
+	if err != nil {
+		return err
+	}
+
+	var selected []*internalpolymorphichelpers.RevisionHistory
+	if o.Revision > 0 {
+		revision, ok := revisions[o.Revision]
+		if !ok {
+			return fmt.Errorf("unable to find the specified revision")
+		}
+		selected = append(selected, revision)
+	} else {
+		for _, revision := range revisions {
+			revision.Template = nil
+			selected = append(selected, revision)
+		}
+		sort.Slice(selected, func(i, j int) bool { return selected[i].Revision < selected[j].Revision })
+	}
+
+	items := make([]interface{}, 0, len(selected))
+	for _, revision := range selected {
+		item, err := runtime.DefaultUnstructuredConverter.ToUnstructured(revision)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	list := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	}}
+
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+	return printer.PrintObj(list, o.Out)
+}
@@ -57,13 +57,17 @@ var (
 
 		Paused resources will not be reconciled by a controller.
 		Use "kubectl rollout resume" to resume a paused resource.
-		Currently  deployments, clonesets support being paused.`)
+		Currently deployments, clonesets, advanced statefulsets, advanced daemonsets, sidecarsets
+		and uniteddeployments support being paused.`)
 
 	pauseExample = templates.Examples(`
 		# Mark the nginx deployment as paused. Any current state of
 		# the deployment will continue its function, new updates to the deployment will not
 		# have an effect as long as the deployment is paused.
-		kubectl-kruise rollout pause deployment/nginx`)
+		kubectl-kruise rollout pause deployment/nginx
+
+		# Mark the nginx Advanced StatefulSet as paused.
+		kubectl-kruise rollout pause statefulset.apps.kruise.io/nginx`)
 )
 
 // NewCmdRolloutPause returns a Command instance for 'rollout pause' sub command
@@ -73,7 +77,7 @@ func NewCmdRolloutPause(f cmdutil.Factory, streams genericclioptions.IOStreams)
 		IOStreams:  streams,
 	}
 
-	validArgs := []string{"deployment", "cloneset"}
+	validArgs := []string{"deployment", "cloneset", "statefulset", "daemonset", "sidecarset", "uniteddeployment"}
 
 	cmd := &cobra.Command{
 		Use:                   "pause RESOURCE",
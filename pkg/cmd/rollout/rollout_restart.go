@@ -19,26 +19,34 @@ package rollout
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
 	"github.com/openkruise/kruise-tools/pkg/cmd/util"
-	"github.com/openkruise/kruise-tools/pkg/fetcher"
+	"github.com/openkruise/kruise-tools/pkg/internal/containerrecreate"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
-	"k8s.io/klog"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/kubectl/pkg/cmd/set"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/interrupt"
 	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // RestartOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
@@ -54,6 +62,11 @@ type RestartOptions struct {
 	Namespace        string
 	EnforceNamespace bool
 
+	InPlace        bool
+	Containers     []string
+	MaxUnavailable string
+	Pods           []string
+
 	resource.FilenameOptions
 	genericclioptions.IOStreams
 }
@@ -62,7 +75,19 @@ var (
 	restartLong = templates.LongDesc(`
 		Restart a resource.
 
-	        Resource will be rollout restarted.`)
+	        Resource will be rollout restarted.
+
+		Kruise workloads are restarted through the kubectl.kruise.io/restartedAt annotation of the
+		pod template. Clonesets, advanced statefulsets and uniteddeployment subsets updating pods in
+		place apply the annotation without restarting any container, so they are refused; restart
+		them with --in-place instead.
+
+		With --in-place, the containers of cloneset and advanced statefulset pods are recreated
+		through ContainerRecreateRequests, without recreating the pods or updating the template.
+
+		With --pods, only the given pods of a cloneset or advanced statefulset are restarted,
+		without updating the template. Cloneset pods are deleted through scaleStrategy.podsToDelete
+		and recreated, and advanced statefulset pods are recreated with the same ordinals.`)
 
 	restartExample = templates.Examples(`
 		# Restart a deployment
@@ -70,7 +95,23 @@ var (
 		kubectl-kruise rollout restart cloneset/abc
 
 		# Restart a daemonset
-		kubectl-kruise rollout restart daemonset/abc`)
+		kubectl-kruise rollout restart daemonset/abc
+
+		# Restart an Advanced StatefulSet and all subsets of a UnitedDeployment
+		kubectl-kruise rollout restart statefulset.apps.kruise.io/abc
+		kubectl-kruise rollout restart uniteddeployment/abc
+
+		# Restart the containers of a cloneset in place, two pods at a time
+		kubectl-kruise rollout restart cloneset/abc --in-place --max-unavailable=2
+
+		# Restart only the sidecar container of an Advanced StatefulSet in place
+		kubectl-kruise rollout restart statefulset.apps.kruise.io/abc --in-place --containers=sidecar
+
+		# Recreate only two pods of a cloneset
+		kubectl-kruise rollout restart cloneset/abc --pods=abc-x8k2p,abc-7dn4q
+
+		# Recreate the pods with ordinals 0 and 3 of an Advanced StatefulSet
+		kubectl-kruise rollout restart statefulset.apps.kruise.io/abc --pods=0,3`)
 )
 
 // NewRolloutRestartOptions returns an initialized RestartOptions instance
@@ -85,7 +126,7 @@ func NewRolloutRestartOptions(streams genericclioptions.IOStreams) *RestartOptio
 func NewCmdRolloutRestart(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
 	o := NewRolloutRestartOptions(streams)
 
-	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset"}
+	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "uniteddeployment"}
 
 	cmd := &cobra.Command{
 		Use:                   "restart RESOURCE",
@@ -104,6 +145,10 @@ func NewCmdRolloutRestart(f cmdutil.Factory, streams genericclioptions.IOStreams
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
 	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().BoolVar(&o.InPlace, "in-place", o.InPlace, "If true, recreate the containers of the pods in place instead of rolling out a new revision. Only supported by clonesets and advanced statefulsets.")
+	cmd.Flags().StringSliceVar(&o.Containers, "containers", o.Containers, "The containers to recreate with --in-place. Defaults to all the containers of the pods.")
+	cmd.Flags().StringSliceVar(&o.Pods, "pods", o.Pods, "The pods to restart, by name or by ordinal for advanced statefulsets. Only supported by clonesets and advanced statefulsets.")
+	cmd.Flags().StringVar(&o.MaxUnavailable, "max-unavailable", o.MaxUnavailable, "The number or percentage of pods to restart at the same time with --in-place, e.g. 2 or 20%. Defaults to 1.")
 	return cmd
 }
 
@@ -133,6 +178,22 @@ func (o *RestartOptions) Validate() error {
 	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
 		return fmt.Errorf("required resource not specified")
 	}
+	if !o.InPlace {
+		if len(o.Containers) > 0 {
+			return fmt.Errorf("--containers can only be used with --in-place")
+		}
+		if len(o.MaxUnavailable) > 0 {
+			return fmt.Errorf("--max-unavailable can only be used with --in-place")
+		}
+		return nil
+	}
+	if len(o.MaxUnavailable) == 0 {
+		return nil
+	}
+	maxUnavailable := intstr.Parse(o.MaxUnavailable)
+	if value, err := intstr.GetValueFromIntOrPercent(&maxUnavailable, 100, true); err != nil || value < 1 {
+		return fmt.Errorf("invalid --max-unavailable %q: must be a positive number or percentage", o.MaxUnavailable)
+	}
 	return nil
 }
 
@@ -161,85 +222,206 @@ func (o RestartOptions) RunRestart() error {
 		// aggregation of errors.
 		allErrs = append(allErrs, err)
 	}
-	cl := util.BaseClient()
 
-	switch infos[0].Object.(type) {
-	case *kruiseappsv1alpha1.CloneSet:
+	if len(o.Pods) > 0 && !o.InPlace {
+		cl := util.BaseClient()
+		for _, info := range infos {
+			if err := o.restartPods(cl.Client, cl.Reader, info); err != nil {
+				allErrs = append(allErrs, fmt.Errorf("failed to restart pods of %s %q: %v", info.Mapping.Resource.Resource, info.Name, err))
+			}
+		}
+		return utilerrors.NewAggregate(allErrs)
+	}
+
+	if o.InPlace {
+		cl := util.BaseClient()
+		for _, info := range infos {
+			if err := o.restartInPlace(cl.Client, cl.Reader, info); err != nil {
+				allErrs = append(allErrs, fmt.Errorf("failed to restart %s %q in place: %v", info.Mapping.Resource.Resource, info.Name, err))
+				continue
+			}
+			printer, err := o.ToPrinter("restarted")
+			if err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+			if err = printer.PrintObj(info.Object, o.Out); err != nil {
+				allErrs = append(allErrs, err)
+			}
+		}
+		return utilerrors.NewAggregate(allErrs)
+	}
 
-		res, found, err := fetcher.GetCloneSetInCache(infos[0].Namespace, infos[0].Name, cl.Reader)
-		if err != nil || !found {
-			klog.Error(err)
-			return fmt.Errorf("failed to retrieve CloneSet %s: %s", infos[0].Name, err.Error())
+	for _, patch := range set.CalculatePatches(infos, scheme.DefaultJSONEncoder(), set.PatchFn(o.Restarter)) {
+		info := patch.Info
+		if patch.Err != nil {
+			resourceString := info.Mapping.Resource.Resource
+			if len(info.Mapping.Resource.Group) > 0 {
+				resourceString = resourceString + "." + info.Mapping.Resource.Group
+			}
+			allErrs = append(allErrs, fmt.Errorf("error: %s %q %v", resourceString, info.Name, patch.Err))
+			continue
 		}
-		internalpolymorphichelpers.UpdateResourceEnv(res)
 
-		if err := cl.Client.Update(context.TODO(), res); err != nil {
-			return err
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			allErrs = append(allErrs, fmt.Errorf("failed to create patch for %v: empty patch", info.Name))
+		}
+
+		obj, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, patch.Patch, nil)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("failed to patch: %v", err))
+			continue
 		}
+
+		info.Refresh(obj, true)
 		printer, err := o.ToPrinter("restarted")
 		if err != nil {
 			allErrs = append(allErrs, err)
+			continue
 		}
-		if err = printer.PrintObj(infos[0].Object, o.Out); err != nil {
+		if err = printer.PrintObj(info.Object, o.Out); err != nil {
 			allErrs = append(allErrs, err)
 		}
-		return utilerrors.NewAggregate(allErrs)
+	}
 
-	case *kruiseappsv1beta1.StatefulSet:
-		res, found, err := fetcher.GetAdvancedStsInCache(infos[0].Namespace, infos[0].Name, cl.Reader)
-		if err != nil || !found {
-			klog.Error(err)
-			return fmt.Errorf("failed to retrieve CloneSet %s: %s", infos[0].Name, err.Error())
-		}
-		internalpolymorphichelpers.UpdateResourceEnv(res)
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// restartInPlace recreates the containers of the pods of the workload through ContainerRecreateRequests,
+// at most MaxUnavailable pods at a time. No more pods are restarted once any of them fails.
+func (o *RestartOptions) restartInPlace(c client.Client, reader client.Reader, info *resource.Info) error {
+	switch info.Object.(type) {
+	case *kruiseappsv1alpha1.CloneSet, *kruiseappsv1beta1.StatefulSet:
+	default:
+		return fmt.Errorf("in-place restart is only supported by clonesets and advanced statefulsets")
+	}
 
-		if err := cl.Client.Update(context.TODO(), res); err != nil {
+	pods, err := internalpolymorphichelpers.ControlledPods(reader, info.Object)
+	if err != nil {
+		return err
+	}
+	if len(o.Pods) > 0 {
+		if pods, err = o.selectPods(info, pods); err != nil {
 			return err
 		}
-		printer, err := o.ToPrinter("restarted")
-		if err != nil {
-			allErrs = append(allErrs, err)
+	}
+	concurrency := 1
+	if len(o.MaxUnavailable) > 0 {
+		maxUnavailable := intstr.Parse(o.MaxUnavailable)
+		if concurrency, err = intstr.GetValueFromIntOrPercent(&maxUnavailable, len(pods), true); err != nil {
+			return err
 		}
-		if err = printer.PrintObj(infos[0].Object, o.Out); err != nil {
-			allErrs = append(allErrs, err)
+		if concurrency < 1 {
+			concurrency = 1
 		}
-		return utilerrors.NewAggregate(allErrs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	intr := interrupt.New(nil, cancel)
+	return intr.Run(func() error {
+		results, err := containerrecreate.RecreatePods(ctx, c, reader, pods, o.Containers, &kruiseappsv1alpha1.ContainerRecreateRequestStrategy{
+			FailurePolicy: kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyFail,
+		}, concurrency, nil)
+		o.printInPlaceResults(pods, results)
+		return err
+	})
+}
+
+// printInPlaceResults prints the result of each pod started by restartInPlace, with the reasons of the failed ones.
+func (o *RestartOptions) printInPlaceResults(pods []*corev1.Pod, results []*kruiseappsv1alpha1.ContainerRecreateRequest) {
+	for i, crr := range results {
+		if crr == nil {
+			continue
+		}
+		if !containerrecreate.IsCompleted(crr) {
+			fmt.Fprintf(o.Out, "pod/%s not restarted in place: containerrecreaterequest %s has not completed\n", pods[i].Name, crr.Name)
+			continue
+		}
+		if failures := containerrecreate.Failures(crr); len(failures) > 0 {
+			fmt.Fprintf(o.Out, "pod/%s failed to restart in place: %s\n", pods[i].Name, strings.Join(failures, "; "))
+			continue
+		}
+		fmt.Fprintf(o.Out, "pod/%s restarted in place\n", pods[i].Name)
+	}
+}
 
+// restartPods recreates the pods of the workload given by --pods without updating its template.
+func (o *RestartOptions) restartPods(c client.Client, reader client.Reader, info *resource.Info) error {
+	switch info.Object.(type) {
+	case *kruiseappsv1alpha1.CloneSet, *kruiseappsv1beta1.StatefulSet:
 	default:
-		for _, patch := range set.CalculatePatches(infos, scheme.DefaultJSONEncoder(), set.PatchFn(o.Restarter)) {
-			info := patch.Info
-			if patch.Err != nil {
-				resourceString := info.Mapping.Resource.Resource
-				if len(info.Mapping.Resource.Group) > 0 {
-					resourceString = resourceString + "." + info.Mapping.Resource.Group
-				}
-				allErrs = append(allErrs, fmt.Errorf("error: %s %q %v", resourceString, info.Name, patch.Err))
-				continue
-			}
+		return fmt.Errorf("restarting pods is only supported by clonesets and advanced statefulsets")
+	}
 
-			if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
-				allErrs = append(allErrs, fmt.Errorf("failed to create patch for %v: empty patch", info.Name))
-			}
+	pods, err := internalpolymorphichelpers.ControlledPods(reader, info.Object)
+	if err != nil {
+		return err
+	}
+	if pods, err = o.selectPods(info, pods); err != nil {
+		return err
+	}
 
-			obj, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, patch.Patch, nil)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("failed to patch: %v", err))
-				continue
+	switch obj := info.Object.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		// the CloneSet controller deletes the pods in podsToDelete and creates new ones to keep the replicas
+		if err := addPodsToDelete(c, reader, obj.DeepCopy(), pods); err != nil {
+			return err
+		}
+	default:
+		// the StatefulSet controller recreates the deleted pods with the same ordinals
+		for _, pod := range pods {
+			if err := c.Delete(context.TODO(), pod); err != nil {
+				return err
 			}
+		}
+	}
 
-			info.Refresh(obj, true)
-			printer, err := o.ToPrinter("restarted")
-			if err != nil {
-				allErrs = append(allErrs, err)
-				continue
-			}
-			if err = printer.PrintObj(info.Object, o.Out); err != nil {
-				allErrs = append(allErrs, err)
+	for _, pod := range pods {
+		fmt.Fprintf(o.Out, "pod/%s restarted\n", pod.Name)
+	}
+	return nil
+}
+
+// addPodsToDelete appends the pods to scaleStrategy.podsToDelete of the CloneSet. The patch carries the
+// resourceVersion, so the pods added by others since cs was read are kept by getting it again on conflicts.
+func addPodsToDelete(c client.Client, reader client.Reader, cs *kruiseappsv1alpha1.CloneSet, pods []*corev1.Pod) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		orig := cs.DeepCopy()
+		podsToDelete := sets.NewString(cs.Spec.ScaleStrategy.PodsToDelete...)
+		for _, pod := range pods {
+			podsToDelete.Insert(pod.Name)
+		}
+		cs.Spec.ScaleStrategy.PodsToDelete = podsToDelete.List()
+		err := c.Patch(context.TODO(), cs, client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{}))
+		if errors.IsConflict(err) {
+			if getErr := reader.Get(context.TODO(), types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}, cs); getErr != nil {
+				return getErr
 			}
 		}
+		return err
+	})
+}
 
-		return utilerrors.NewAggregate(allErrs)
-
+// selectPods returns the pods given by --pods, either by name, or by ordinal for advanced statefulsets.
+func (o *RestartOptions) selectPods(info *resource.Info, pods []*corev1.Pod) ([]*corev1.Pod, error) {
+	byName := make(map[string]*corev1.Pod, len(pods))
+	for _, pod := range pods {
+		byName[pod.Name] = pod
 	}
 
+	var selected []*corev1.Pod
+	for _, name := range o.Pods {
+		if _, isStatefulSet := info.Object.(*kruiseappsv1beta1.StatefulSet); isStatefulSet {
+			if ordinal, err := strconv.Atoi(name); err == nil {
+				name = fmt.Sprintf("%s-%d", info.Name, ordinal)
+			}
+		}
+		pod, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("pod %s not found in %s %s", name, info.Mapping.Resource.Resource, info.Name)
+		}
+		selected = append(selected, pod)
+	}
+	return selected, nil
 }
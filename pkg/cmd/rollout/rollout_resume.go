@@ -58,7 +58,8 @@ var (
 
 		Paused resources will not be reconciled by a controller. By resuming a
 		resource, we allow it to be reconciled again.
-		Currently deployments, cloneset support being resumed.`)
+		Currently deployments, clonesets, advanced statefulsets, advanced daemonsets, sidecarsets
+		and uniteddeployments support being resumed.`)
 
 	resumeExample = templates.Examples(`
 		# Resume an already paused deployment
@@ -79,7 +80,7 @@ func NewRolloutResumeOptions(streams genericclioptions.IOStreams) *ResumeOptions
 func NewCmdRolloutResume(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
 	o := NewRolloutResumeOptions(streams)
 
-	validArgs := []string{"deployment", "cloneset"}
+	validArgs := []string{"deployment", "cloneset", "statefulset", "daemonset", "sidecarset", "uniteddeployment"}
 
 	cmd := &cobra.Command{
 		Use:                   "resume RESOURCE",
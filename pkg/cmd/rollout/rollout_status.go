@@ -19,9 +19,15 @@ package rollout
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"time"
 
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/fetcher"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
 
@@ -31,8 +37,11 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/cache"
@@ -53,7 +62,10 @@ var (
 		you can use --watch=false. Note that if a new rollout starts in-between, then
 		'rollout status' will continue watching the latest revision. If you want to
 		pin to a specific revision and abort if it is rolled over by another revision,
-		use --revision=N where N is the revision you need to watch for.`)
+		use --revision=N where N is the revision you need to watch for.
+
+		With --selector or --all, the rollout status of all the matching workloads is watched
+		at once and shown as a table, until all of them are done or any of them fails.`)
 
 	statusExample = templates.Examples(`
 		# Watch the rollout status of a deployment
@@ -63,7 +75,37 @@ var (
 		kubectl-kruise rollout status cloneset/nginx
 
 		# Watch the rollout status of a advanced statefulset
-		kubectl-kruise rollout status asts/nginx`)
+		kubectl-kruise rollout status asts/nginx
+
+		# Watch the rollout status of an advanced daemonset, a uniteddeployment and a sidecarset
+		kubectl-kruise rollout status daemonset.apps.kruise.io/nginx
+		kubectl-kruise rollout status uniteddeployment/nginx
+		kubectl-kruise rollout status sidecarset/nginx
+
+		# Watch a broadcastjob until all its pods finished
+		kubectl-kruise rollout status broadcastjob/warmup
+
+		# Show the rollout status of each pod of a cloneset, e.g. whether it is updated in place
+		kubectl-kruise rollout status cloneset/nginx --pods
+
+		# Watch the rollout status of all the workloads labeled app-group=checkout
+		kubectl-kruise rollout status -l app-group=checkout
+
+		# Watch the rollout status of all the clonesets in the namespace
+		kubectl-kruise rollout status cloneset --all`)
+
+	// defaultStatusResources are the workloads watched by --selector and --all if no resource type is given
+	defaultStatusResources = []string{
+		"deployments.apps",
+		"statefulsets.apps",
+		"daemonsets.apps",
+		"clonesets.apps.kruise.io",
+		"statefulsets.apps.kruise.io",
+		"daemonsets.apps.kruise.io",
+		"uniteddeployments.apps.kruise.io",
+		"sidecarsets.apps.kruise.io",
+		"broadcastjobs.apps.kruise.io",
+	}
 )
 
 // RolloutStatusOptions holds the command-line options for 'rollout status' sub command
@@ -77,6 +119,11 @@ type RolloutStatusOptions struct {
 	Watch    bool
 	Revision int64
 	Timeout  time.Duration
+	Selector string
+	All      bool
+	Pods     bool
+
+	StatusResources []string
 
 	StatusViewerFn func(*meta.RESTMapping) (internalpolymorphichelpers.StatusViewer, error)
 	Builder        func() *resource.Builder
@@ -101,7 +148,7 @@ func NewRolloutStatusOptions(streams genericclioptions.IOStreams) *RolloutStatus
 func NewCmdRolloutStatus(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
 	o := NewRolloutStatusOptions(streams)
 
-	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset"}
+	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset", "uniteddeployment", "sidecarset", "broadcastjob"}
 
 	cmd := &cobra.Command{
 		Use:                   "status (TYPE NAME | TYPE/NAME) [flags]",
@@ -122,6 +169,9 @@ func NewCmdRolloutStatus(f cmdutil.Factory, streams genericclioptions.IOStreams)
 	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", o.Watch, "Watch the status of the rollout until it's done.")
 	cmd.Flags().Int64Var(&o.Revision, "revision", o.Revision, "Pin to a specific revision for showing its status. Defaults to 0 (last revision).")
 	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The length of time to wait before ending watch, zero means never. Any other values should contain a corresponding time unit (e.g. 1s, 2m, 3h).")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2). The matching workloads are watched at once.")
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Watch the rollout status of all the workloads in the namespace at once.")
+	cmd.Flags().BoolVar(&o.Pods, "pods", o.Pods, "Show the rollout status of each pod once instead of watching, including whether it is updated in place or recreated. Only supported by clonesets.")
 
 	return cmd
 }
@@ -137,7 +187,30 @@ func (o *RolloutStatusOptions) Complete(f cmdutil.Factory, args []string) error
 	}
 
 	o.BuilderArgs = args
-	o.StatusViewerFn = internalpolymorphichelpers.StatusViewerFn
+	o.StatusViewerFn = func(mapping *meta.RESTMapping) (internalpolymorphichelpers.StatusViewer, error) {
+		statusViewer, err := internalpolymorphichelpers.StatusViewerFn(mapping)
+		if daemonStatusViewer, ok := statusViewer.(*internalpolymorphichelpers.AdvancedDaemonSetStatusViewer); ok {
+			// rolling updates limited by rollingUpdate.selector are done once the pods on the selected nodes are updated
+			daemonStatusViewer.CountSelectedNodes = internalpolymorphichelpers.SelectedDaemonNodesCounter(util.BaseClient().Reader)
+		}
+		return statusViewer, err
+	}
+
+	if o.watchesMany() {
+		o.StatusResources = args
+		if len(o.StatusResources) == 0 {
+			mapper, err := f.ToRESTMapper()
+			if err != nil {
+				return err
+			}
+			// skip the workloads not installed in the cluster, e.g. Kruise ones
+			for _, r := range defaultStatusResources {
+				if _, err := mapper.KindFor(schema.ParseGroupResource(r).WithVersion("")); err == nil {
+					o.StatusResources = append(o.StatusResources, r)
+				}
+			}
+		}
+	}
 
 	clientConfig, err := f.ToRESTConfig()
 	if err != nil {
@@ -154,19 +227,43 @@ func (o *RolloutStatusOptions) Complete(f cmdutil.Factory, args []string) error
 
 // Validate makes sure all the provided values for command-line options are valid
 func (o *RolloutStatusOptions) Validate() error {
-	if len(o.BuilderArgs) == 0 && cmdutil.IsFilenameSliceEmpty(o.FilenameOptions.Filenames, o.FilenameOptions.Kustomize) {
-		return fmt.Errorf("required resource not specified")
-	}
-
 	if o.Revision < 0 {
 		return fmt.Errorf("revision must be a positive integer: %v", o.Revision)
 	}
 
+	if o.watchesMany() {
+		if len(o.Selector) > 0 && o.All {
+			return fmt.Errorf("--selector and --all cannot be used together")
+		}
+		if o.Revision > 0 {
+			return fmt.Errorf("--revision cannot be used with --selector or --all")
+		}
+		if !cmdutil.IsFilenameSliceEmpty(o.FilenameOptions.Filenames, o.FilenameOptions.Kustomize) {
+			return fmt.Errorf("--filename cannot be used with --selector or --all")
+		}
+		if o.Pods {
+			return fmt.Errorf("--pods cannot be used with --selector or --all")
+		}
+		return nil
+	}
+
+	if len(o.BuilderArgs) == 0 && cmdutil.IsFilenameSliceEmpty(o.FilenameOptions.Filenames, o.FilenameOptions.Kustomize) {
+		return fmt.Errorf("required resource not specified")
+	}
 	return nil
 }
 
+// watchesMany returns whether the rollout status of many workloads is watched at once.
+func (o *RolloutStatusOptions) watchesMany() bool {
+	return len(o.Selector) > 0 || o.All
+}
+
 // Run performs the execution of 'rollout status' sub command
 func (o *RolloutStatusOptions) Run() error {
+	if o.watchesMany() {
+		return o.runMany()
+	}
+
 	r := o.Builder().
 		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
 		NamespaceParam(o.Namespace).DefaultNamespace().
@@ -188,22 +285,226 @@ func (o *RolloutStatusOptions) Run() error {
 		return fmt.Errorf("rollout status is only supported on individual resources and resource collections - %d resources were found", len(infos))
 	}
 	info := infos[0]
-	mapping := info.ResourceMapping()
 
-	statusViewer, err := o.StatusViewerFn(mapping)
+	statusViewer, err := o.StatusViewerFn(info.ResourceMapping())
+	if err != nil {
+		return err
+	}
+
+	if o.Pods {
+		return o.printPodStatuses(info, statusViewer)
+	}
+
+	// if the rollout isn't done yet, keep watching deployment status
+	ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), o.Timeout)
+	intr := interrupt.New(nil, cancel)
+	return intr.Run(func() error {
+		return watchRolloutStatus(ctx, o.DynamicClient, info, statusViewer, o.Revision, o.Watch, o.Out)
+	})
+}
+
+// printPodStatuses prints the current rollout status of the CloneSet in info, followed by the status of each of its pods.
+func (o *RolloutStatusOptions) printPodStatuses(info *resource.Info, statusViewer internalpolymorphichelpers.StatusViewer) error {
+	if _, ok := info.Object.(*kruiseappsv1alpha1.CloneSet); !ok {
+		return fmt.Errorf("--pods is only supported by clonesets")
+	}
+	if err := watchRolloutStatus(context.Background(), o.DynamicClient, info, statusViewer, o.Revision, false, o.Out); err != nil {
+		return err
+	}
+
+	cl := util.BaseClient()
+	cs, found, err := fetcher.GetCloneSetInCache(info.Namespace, info.Name, cl.Reader)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("cloneset %s not found", info.Name)
+	}
+	pods, err := internalpolymorphichelpers.ControlledPods(cl.Reader, cs)
+	if err != nil {
+		return err
+	}
+
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	fmt.Fprintln(w, "NAME\tREVISION\tUPDATED\tUPDATE-TYPE\tINPLACE-READY\tREADY\tRESTARTS")
+	for _, status := range internalpolymorphichelpers.CloneSetPodRolloutStatuses(cs, pods) {
+		fmt.Fprintf(w, "%s\t%s\t%v\t%s\t%s\t%d/%d\t%d\n", status.Name, util.ValueOrNone(status.Revision), status.Updated,
+			util.ValueOrNone(status.UpdateType), util.ValueOrNone(string(status.InPlaceUpdateReady)), status.ReadyContainers, status.Containers, status.Restarts)
+	}
+	return nil
+}
+
+// rolloutState is the latest rollout status of one of the workloads watched at once.
+type rolloutState struct {
+	name    string
+	message string
+	done    bool
+	err     error
+}
+
+func (s *rolloutState) status() string {
+	switch {
+	case s.err == context.DeadlineExceeded:
+		return "TimedOut"
+	case s.err == context.Canceled:
+		return "Aborted"
+	case s.err != nil:
+		return "Failed"
+	case s.done:
+		return "Done"
+	default:
+		return "Progressing"
+	}
+}
+
+// runMany watches the rollout status of all the workloads selected by --selector or --all concurrently,
+// until all of them are done, or any of them fails or times out.
+func (o *RolloutStatusOptions) runMany() error {
+	if len(o.StatusResources) == 0 {
+		return fmt.Errorf("no workload resources found")
+	}
+	r := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		NamespaceParam(o.Namespace).DefaultNamespace().
+		LabelSelectorParam(o.Selector).
+		SelectAllParam(o.All).
+		ResourceTypeOrNameArgs(false, strings.Join(o.StatusResources, ",")).
+		ContinueOnError().
+		Latest().
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return err
+	}
+	infos, err := r.Infos()
 	if err != nil {
 		return err
 	}
+	if len(infos) == 0 {
+		fmt.Fprintf(o.ErrOut, "No resources found in %s namespace.\n", o.Namespace)
+		return nil
+	}
+
+	statusViewers := make([]internalpolymorphichelpers.StatusViewer, len(infos))
+	states := make([]*rolloutState, len(infos))
+	for i, info := range infos {
+		if statusViewers[i], err = o.StatusViewerFn(info.ResourceMapping()); err != nil {
+			return err
+		}
+		states[i] = &rolloutState{name: info.ObjectName(), message: "Waiting for the rollout status"}
+		if len(info.Mapping.Resource.Group) > 0 {
+			states[i].name = info.Mapping.Resource.Resource + "." + info.Mapping.Resource.Group + "/" + info.Name
+		}
+	}
+
+	timeoutCtx, cancelTimeout := watchtools.ContextWithOptionalTimeout(context.Background(), o.Timeout)
+	defer cancelTimeout()
+	// canceled once any of the rollouts fails, so that the others stop being watched
+	ctx, cancel := context.WithCancel(timeoutCtx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		changed = make(chan struct{}, 1)
+	)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	for i := range infos {
+		wg.Add(1)
+		go func(info *resource.Info, statusViewer internalpolymorphichelpers.StatusViewer, state *rolloutState) {
+			defer wg.Done()
+			err := untilRolloutDone(ctx, o.DynamicClient, info, statusViewer, 0, o.Watch, func(status string, done bool) {
+				mu.Lock()
+				state.message, state.done = strings.TrimSpace(status), done
+				mu.Unlock()
+				notify()
+			})
+			if err != nil {
+				mu.Lock()
+				if ctx.Err() != nil {
+					// the watch is stopped by the timeout or by the failure of another rollout
+					state.err = ctx.Err()
+					if timeoutCtx.Err() != nil {
+						state.err = timeoutCtx.Err()
+					}
+				} else {
+					state.err = err
+					state.message = err.Error()
+				}
+				mu.Unlock()
+				cancel()
+			}
+			notify()
+		}(infos[i], statusViewers[i], states[i])
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	table := util.NewLiveTable(o.Out, "NAME", "STATUS", "MESSAGE")
+	intr := interrupt.New(nil, cancel)
+	return intr.Run(func() error {
+		for {
+			select {
+			case <-changed:
+				mu.Lock()
+				table.Render(rolloutRows(states))
+				mu.Unlock()
+			case <-finished:
+				table.Render(rolloutRows(states))
+				var errs []error
+				for _, state := range states {
+					if state.err != nil && state.err != context.Canceled {
+						errs = append(errs, fmt.Errorf("%s: %s", state.name, strings.ToLower(state.status())))
+					}
+				}
+				return utilerrors.NewAggregate(errs)
+			}
+		}
+	})
+}
+
+// rolloutRows returns the rows of the rollout states in a table.
+func rolloutRows(states []*rolloutState) []util.TableRow {
+	rows := make([]util.TableRow, 0, len(states))
+	for _, state := range states {
+		rows = append(rows, util.TableRow{Key: state.name, Cells: []string{state.name, state.status(), util.FirstLine(state.message)}})
+	}
+	return rows
+}
+
+// watchRolloutStatus prints the rollout status of the object in info each time it changes,
+// until statusViewer considers the rollout done, or only once if shouldWatch is false.
+func watchRolloutStatus(ctx context.Context, client dynamic.Interface, info *resource.Info,
+	statusViewer internalpolymorphichelpers.StatusViewer, revision int64, shouldWatch bool, out io.Writer) error {
+	return untilRolloutDone(ctx, client, info, statusViewer, revision, shouldWatch, func(status string, done bool) {
+		fmt.Fprintf(out, "%s", status)
+	})
+}
 
+// untilRolloutDone calls onStatus with the rollout status of the object in info each time it changes,
+// until statusViewer considers the rollout done, or only once if shouldWatch is false.
+func untilRolloutDone(ctx context.Context, client dynamic.Interface, info *resource.Info,
+	statusViewer internalpolymorphichelpers.StatusViewer, revision int64, shouldWatch bool, onStatus func(status string, done bool)) error {
+	mapping := info.ResourceMapping()
 	fieldSelector := fields.OneTermEqualSelector("metadata.name", info.Name).String()
 	lw := &cache.ListWatch{
 		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
 			options.FieldSelector = fieldSelector
-			return o.DynamicClient.Resource(info.Mapping.Resource).Namespace(info.Namespace).List(context.TODO(), options)
+			return client.Resource(info.Mapping.Resource).Namespace(info.Namespace).List(context.TODO(), options)
 		},
 		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
 			options.FieldSelector = fieldSelector
-			return o.DynamicClient.Resource(info.Mapping.Resource).Namespace(info.Namespace).Watch(context.TODO(), options)
+			return client.Resource(info.Mapping.Resource).Namespace(info.Namespace).Watch(context.TODO(), options)
 		},
 	}
 
@@ -221,38 +522,32 @@ func (o *RolloutStatusOptions) Run() error {
 		return false, nil
 	}
 
-	// if the rollout isn't done yet, keep watching deployment status
-	ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), o.Timeout)
-	intr := interrupt.New(nil, cancel)
-	return intr.Run(func() error {
-		_, err = watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, preconditionFunc, func(e watch.Event) (bool, error) {
-			switch t := e.Type; t {
-			case watch.Added, watch.Modified:
-				status, done, err := statusViewer.Status(e.Object.(runtime.Unstructured), o.Revision)
-				if err != nil {
-					return false, err
-				}
-				fmt.Fprintf(o.Out, "%s", status)
-				// Quit waiting if the rollout is done
-				if done {
-					return true, nil
-				}
+	_, err := watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, preconditionFunc, func(e watch.Event) (bool, error) {
+		switch t := e.Type; t {
+		case watch.Added, watch.Modified:
+			status, done, err := statusViewer.Status(e.Object.(runtime.Unstructured), revision)
+			if err != nil {
+				return false, err
+			}
+			onStatus(status, done)
+			// Quit waiting if the rollout is done
+			if done {
+				return true, nil
+			}
 
-				shouldWatch := o.Watch
-				if !shouldWatch {
-					return true, nil
-				}
+			if !shouldWatch {
+				return true, nil
+			}
 
-				return false, nil
+			return false, nil
 
-			case watch.Deleted:
-				// We need to abort to avoid cases of recreation and not to silently watch the wrong (new) object
-				return true, fmt.Errorf("object has been deleted")
+		case watch.Deleted:
+			// We need to abort to avoid cases of recreation and not to silently watch the wrong (new) object
+			return true, fmt.Errorf("object has been deleted")
 
-			default:
-				return true, fmt.Errorf("internal error: unexpected event %#v", e)
-			}
-		})
-		return err
+		default:
+			return true, fmt.Errorf("internal error: unexpected event %#v", e)
+		}
 	})
+	return err
 }
@@ -20,13 +20,17 @@ package rollout
 import (
 	"fmt"
 
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/i18n"
@@ -36,11 +40,16 @@ import (
 // UndoOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
 // referencing the cmd.Flags()
 type UndoOptions struct {
-	PrintFlags *genericclioptions.PrintFlags
-	ToPrinter  func(string) (printers.ResourcePrinter, error)
+	PrintFlags  *genericclioptions.PrintFlags
+	ToPrinter   func(string) (printers.ResourcePrinter, error)
+	RecordFlags *genericclioptions.RecordFlags
+	Recorder    genericclioptions.Recorder
 
 	Builder          func() *resource.Builder
 	ToRevision       int64
+	ToRevisionName   string
+	ToImages         []string
+	RevisionSelector *internalpolymorphichelpers.RevisionSelector
 	DryRunStrategy   cmdutil.DryRunStrategy
 	DryRunVerifier   *resource.DryRunVerifier
 	Resources        []string
@@ -63,6 +72,21 @@ var (
 		# Rollback to the previous Advanced StatefulSet
 		kubectl-kruise rollout undo asts/abc
 
+		# Rollback to uniteddeployment revision 2
+		kubectl-kruise rollout undo uniteddeployment/abc --to-revision=2
+
+		# Rollback the cloneset to the revision recorded by ControllerRevision abc-5c8f7d9b4
+		kubectl-kruise rollout undo cloneset/abc --to-revision-name=abc-5c8f7d9b4
+
+		# Rollback the Advanced StatefulSet to the latest revision running nginx:1.19 in container app
+		kubectl-kruise rollout undo asts/abc --to-image=app=nginx:1.19
+
+		# Rollback the cloneset and record this command as the change-cause instead of the one of the revision
+		kubectl-kruise rollout undo cloneset/abc --record
+
+		# Rollback to the previous advanced daemonset
+		kubectl-kruise rollout undo daemonset.apps.kruise.io/abc
+
 		# Rollback to daemonset revision 3
 		kubectl-kruise rollout undo daemonset/abc --to-revision=3
 
@@ -73,9 +97,11 @@ var (
 // NewRolloutUndoOptions returns an initialized UndoOptions instance
 func NewRolloutUndoOptions(streams genericclioptions.IOStreams) *UndoOptions {
 	return &UndoOptions{
-		PrintFlags: genericclioptions.NewPrintFlags("rolled back").WithTypeSetter(internalapi.GetScheme()),
-		IOStreams:  streams,
-		ToRevision: int64(0),
+		PrintFlags:  genericclioptions.NewPrintFlags("rolled back").WithTypeSetter(internalapi.GetScheme()),
+		RecordFlags: genericclioptions.NewRecordFlags(),
+		Recorder:    genericclioptions.NoopRecorder{},
+		IOStreams:   streams,
+		ToRevision:  int64(0),
 	}
 }
 
@@ -83,7 +109,7 @@ func NewRolloutUndoOptions(streams genericclioptions.IOStreams) *UndoOptions {
 func NewCmdRolloutUndo(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
 	o := NewRolloutUndoOptions(streams)
 
-	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset"}
+	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset", "uniteddeployment"}
 
 	cmd := &cobra.Command{
 		Use:                   "undo (TYPE NAME | TYPE/NAME) [flags]",
@@ -100,10 +126,13 @@ func NewCmdRolloutUndo(f cmdutil.Factory, streams genericclioptions.IOStreams) *
 	}
 
 	cmd.Flags().Int64Var(&o.ToRevision, "to-revision", o.ToRevision, "The revision to rollback to. Default to 0 (last revision).")
+	cmd.Flags().StringVar(&o.ToRevisionName, "to-revision-name", o.ToRevisionName, "The name of the ControllerRevision to rollback to. Only supported by clonesets and advanced statefulsets.")
+	cmd.Flags().StringSliceVar(&o.ToImages, "to-image", o.ToImages, "Rollback to the latest revision with the given container images, e.g. app=nginx:1.19. Only supported by clonesets and advanced statefulsets.")
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
 	cmdutil.AddDryRunFlag(cmd)
 	o.PrintFlags.AddFlags(cmd)
+	o.RecordFlags.AddFlags(cmd)
 	return cmd
 }
 
@@ -111,6 +140,12 @@ func NewCmdRolloutUndo(f cmdutil.Factory, streams genericclioptions.IOStreams) *
 func (o *UndoOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
 	o.Resources = args
 	var err error
+	if err = o.RecordFlags.Complete(cmd); err != nil {
+		return err
+	}
+	if o.Recorder, err = o.RecordFlags.ToRecorder(); err != nil {
+		return err
+	}
 	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
 	if err != nil {
 		return err
@@ -135,6 +170,14 @@ func (o *UndoOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []str
 		return o.PrintFlags.ToPrinter()
 	}
 
+	if len(o.ToRevisionName) > 0 || len(o.ToImages) > 0 {
+		images, _, err := cmdutil.ParsePairs(o.ToImages, "image", false)
+		if err != nil {
+			return err
+		}
+		o.RevisionSelector = &internalpolymorphichelpers.RevisionSelector{Name: o.ToRevisionName, Images: images}
+	}
+
 	o.RESTClientGetter = f
 	o.Builder = f.NewBuilder
 
@@ -145,6 +188,12 @@ func (o *UndoOptions) Validate() error {
 	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
 		return fmt.Errorf("required resource not specified")
 	}
+	if len(o.ToRevisionName) > 0 && len(o.ToImages) > 0 {
+		return fmt.Errorf("--to-revision-name and --to-image cannot be used together")
+	}
+	if o.RevisionSelector != nil && o.ToRevision != 0 {
+		return fmt.Errorf("--to-revision cannot be used with --to-revision-name or --to-image")
+	}
 	return nil
 }
 
@@ -177,7 +226,24 @@ func (o *UndoOptions) RunUndo() error {
 				return err
 			}
 		}
-		result, err := rollbacker.Rollback(info.Object, nil, o.ToRevision, o.DryRunStrategy)
+		// with --record, this command is recorded as the change-cause instead of the one of the revision rolled back to
+		var updatedAnnotations map[string]string
+		if o.RecordFlags.Record != nil && *o.RecordFlags.Record {
+			if err := o.Recorder.Record(info.Object); err != nil {
+				klog.V(4).Infof("error recording current command: %v", err)
+			} else if accessor, err := meta.Accessor(info.Object); err == nil {
+				updatedAnnotations = map[string]string{
+					internalpolymorphichelpers.ChangeCauseAnnotation: accessor.GetAnnotations()[internalpolymorphichelpers.ChangeCauseAnnotation],
+				}
+			}
+		}
+		toRevision := o.ToRevision
+		if o.RevisionSelector != nil {
+			if toRevision, err = o.findRevision(info); err != nil {
+				return err
+			}
+		}
+		result, err := rollbacker.Rollback(info.Object, updatedAnnotations, toRevision, o.DryRunStrategy)
 		if err != nil {
 			return err
 		}
@@ -192,3 +258,17 @@ func (o *UndoOptions) RunUndo() error {
 
 	return err
 }
+
+// findRevision resolves the revision selected by --to-revision-name or --to-image.
+func (o *UndoOptions) findRevision(info *resource.Info) (int64, error) {
+	switch info.Object.(type) {
+	case *kruiseappsv1alpha1.CloneSet, *kruiseappsv1beta1.StatefulSet:
+	default:
+		return 0, fmt.Errorf("--to-revision-name and --to-image are only supported by clonesets and advanced statefulsets")
+	}
+	historyViewer, err := internalpolymorphichelpers.HistoryViewerFn(o.RESTClientGetter, info.Mapping)
+	if err != nil {
+		return 0, err
+	}
+	return internalpolymorphichelpers.FindRevision(historyViewer, info.Namespace, info.Name, *o.RevisionSelector)
+}
@@ -17,8 +17,14 @@ limitations under the License.
 package set
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/internal/imagepulljob"
 	"github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	kresource "github.com/openkruise/kruise-tools/pkg/resource"
 	"github.com/spf13/cobra"
@@ -27,6 +33,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
@@ -35,7 +42,7 @@ import (
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/i18n"
 	"k8s.io/kubectl/pkg/util/templates"
-
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // SetImageOptions ImageOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
@@ -54,10 +61,16 @@ type SetImageOptions struct {
 	Output         string
 	Local          bool
 	ResolveImage   ImageResolver
+	Preheat        bool
+	PreheatTimeout time.Duration
 
 	PrintObj printers.ResourcePrinterFunc
 	Recorder genericclioptions.Recorder
 
+	RecordRevisionChangeCause func(runtime.Object) error
+	PreheatClient             client.Client
+	PreheatReader             client.Reader
+
 	UpdatePodSpecForObject polymorphichelpers.UpdatePodSpecForObjectFunc
 	Resources              []string
 	ContainerImages        map[string]string
@@ -85,6 +98,9 @@ var (
 		# Update image of all containers of cloneset sample to 'nginx:1.9.1'
 		kubectl-kruise set image cloneset sample *=nginx:1.9.1
 
+		# Pull the new image on the nodes running the pods of cloneset sample before updating it
+		kubectl-kruise set image cloneset/sample nginx=nginx:1.9.1 --preheat --preheat-timeout=10m
+
 		# Print result (in yaml format) of updating nginx container image from local file, without hitting the server
 		kubectl-kruise set image -f path/to/file.yaml nginx=nginx:1.9.1 --local -o yaml`)
 )
@@ -95,6 +111,8 @@ func NewImageOptions(streams genericclioptions.IOStreams) *SetImageOptions {
 		PrintFlags:  genericclioptions.NewPrintFlags("image updated").WithTypeSetter(scheme.Scheme),
 		RecordFlags: genericclioptions.NewRecordFlags(),
 
+		PreheatTimeout: 5 * time.Minute,
+
 		Recorder: genericclioptions.NoopRecorder{},
 
 		IOStreams: streams,
@@ -126,6 +144,8 @@ func NewCmdImage(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.
 	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources, including uninitialized ones, in the namespace of the specified resource types")
 	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) to filter on, not including uninitialized ones, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
 	cmd.Flags().BoolVar(&o.Local, "local", o.Local, "If true, set image will NOT contact api-server but run locally.")
+	cmd.Flags().BoolVar(&o.Preheat, "preheat", o.Preheat, "If true, pull the new images on the nodes running the pods of the resources with ImagePullJobs before updating the resources.")
+	cmd.Flags().DurationVar(&o.PreheatTimeout, "preheat-timeout", o.PreheatTimeout, "The length of time to wait for the images to be pulled with --preheat before updating the resources anyway, zero means never.")
 	cmdutil.AddDryRunFlag(cmd)
 	return cmd
 }
@@ -158,6 +178,20 @@ func (o *SetImageOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args [
 		return err
 	}
 	o.DryRunVerifier = resource.NewDryRunVerifier(dynamicClient, discoveryClient)
+	if o.RecordFlags != nil && o.RecordFlags.Record != nil && *o.RecordFlags.Record {
+		clientset, err := f.KubernetesClientSet()
+		if err != nil {
+			return err
+		}
+		o.RecordRevisionChangeCause = func(obj runtime.Object) error {
+			return polymorphichelpers.RecordRevisionChangeCause(clientset, obj)
+		}
+	}
+	if o.Preheat && !o.Local && o.DryRunStrategy == cmdutil.DryRunNone {
+		cl := util.BaseClient()
+		o.PreheatClient = cl.Client
+		o.PreheatReader = cl.Reader
+	}
 	o.Output = cmdutil.GetFlagString(cmd, "output")
 	o.ResolveImage = resolveImageFunc
 
@@ -225,15 +259,24 @@ func (o *SetImageOptions) Validate() error {
 	if o.Local && o.DryRunStrategy == cmdutil.DryRunServer {
 		errors = append(errors, fmt.Errorf("cannot specify --local and --dry-run=server - did you mean --dry-run=client?"))
 	}
+	if o.Local && o.Preheat {
+		errors = append(errors, fmt.Errorf("cannot specify --local and --preheat"))
+	}
+	if o.PreheatTimeout < 0 {
+		errors = append(errors, fmt.Errorf("--preheat-timeout must not be negative"))
+	}
 	return utilerrors.NewAggregate(errors)
 }
 
 // Run performs the execution of 'set image' sub command
 func (o *SetImageOptions) Run() error {
 	var allErrs []error
+	// the images each object is updated to, which are preheated with --preheat
+	updatedImages := map[runtime.Object][]string{}
 
 	patches := CalculatePatches(o.Infos, scheme.DefaultJSONEncoder(), func(obj runtime.Object) ([]byte, error) {
 		_, err := o.UpdatePodSpecForObject(obj, func(spec *corev1.PodSpec) error {
+			orig := spec.DeepCopy()
 			for name, image := range o.ContainerImages {
 				resolvedImageName, err := o.ResolveImage(image)
 				if err != nil {
@@ -250,6 +293,7 @@ func (o *SetImageOptions) Run() error {
 					allErrs = append(allErrs, fmt.Errorf("error: unable to find container named %q", name))
 				}
 			}
+			updatedImages[obj] = changedImages(orig, spec)
 			return nil
 		})
 		if err != nil {
@@ -289,6 +333,13 @@ func (o *SetImageOptions) Run() error {
 			}
 		}
 
+		if o.PreheatClient != nil {
+			// preheating is best effort, the update goes on anyway
+			if err := o.preheatImages(info, updatedImages[info.Object]); err != nil {
+				fmt.Fprintf(o.ErrOut, "warning: failed to preheat images of %s: %v\n", info.ObjectName(), err)
+			}
+		}
+
 		// patch the change
 		actual, err := kresource.
 			NewHelper(info.Client, info.Mapping).
@@ -299,6 +350,14 @@ func (o *SetImageOptions) Run() error {
 			continue
 		}
 
+		// a Kruise workload updated back to an existing revision reuses its ControllerRevision,
+		// so the change-cause has to be recorded onto it as well
+		if o.DryRunStrategy == cmdutil.DryRunNone && o.RecordRevisionChangeCause != nil {
+			if err := o.RecordRevisionChangeCause(actual); err != nil {
+				klog.V(4).Infof("error recording change-cause to revision: %v", err)
+			}
+		}
+
 		if err := o.PrintObj(actual, o.Out); err != nil {
 			allErrs = append(allErrs, err)
 		}
@@ -307,6 +366,96 @@ func (o *SetImageOptions) Run() error {
 	return utilerrors.NewAggregate(allErrs)
 }
 
+// preheatImages pulls the images on the nodes currently running the pods of the object in info,
+// and waits until they are pulled or the preheat timeout expires.
+func (o *SetImageOptions) preheatImages(info *resource.Info, images []string) error {
+	if len(images) == 0 {
+		return nil
+	}
+	nodes, err := o.nodesRunningPods(info)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if o.PreheatTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), o.PreheatTimeout)
+	}
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	selector := &kruiseappsv1alpha1.ImagePullJobNodeSelector{Names: nodes}
+	for _, image := range images {
+		job := imagepulljob.NewJob(info.Namespace, image, selector, nil, o.PreheatTimeout)
+		err := o.PreheatClient.Create(ctx, job)
+		mu.Lock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create imagepulljob for %s: %v", image, err))
+			mu.Unlock()
+			continue
+		}
+		fmt.Fprintf(o.ErrOut, "preheating %s on %d nodes with imagepulljob %s\n", image, len(nodes), job.Name)
+		mu.Unlock()
+
+		wg.Add(1)
+		go func(job *kruiseappsv1alpha1.ImagePullJob) {
+			defer wg.Done()
+			job, err := imagepulljob.Wait(ctx, o.PreheatReader, job, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			fmt.Fprintf(o.ErrOut, "preheated %s: %s\n", job.Spec.Image, imagepulljob.Progress(job))
+		}(job)
+	}
+	wg.Wait()
+	return utilerrors.NewAggregate(errs)
+}
+
+// nodesRunningPods returns the names of the nodes the pods of the object in info are scheduled to, sorted.
+func (o *SetImageOptions) nodesRunningPods(info *resource.Info) ([]string, error) {
+	pods := []*corev1.Pod{}
+	if pod, ok := info.Object.(*corev1.Pod); ok {
+		pods = append(pods, pod)
+	} else {
+		var err error
+		if pods, err = polymorphichelpers.ControlledPods(o.PreheatReader, info.Object); err != nil {
+			return nil, err
+		}
+	}
+	nodes := sets.NewString()
+	for _, pod := range pods {
+		if len(pod.Spec.NodeName) > 0 {
+			nodes.Insert(pod.Spec.NodeName)
+		}
+	}
+	return nodes.List(), nil
+}
+
+// changedImages returns the images of the containers in spec which differ from orig, sorted.
+func changedImages(orig, spec *corev1.PodSpec) []string {
+	origImages := map[string]string{}
+	for _, c := range append(append([]corev1.Container{}, orig.InitContainers...), orig.Containers...) {
+		origImages[c.Name] = c.Image
+	}
+	images := sets.NewString()
+	for _, c := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+		if origImages[c.Name] != c.Image {
+			images.Insert(c.Image)
+		}
+	}
+	return images.List()
+}
+
 func setImage(containers []corev1.Container, containerName string, image string) bool {
 	containerFound := false
 	// Find the container to update, and update its image
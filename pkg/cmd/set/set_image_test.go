@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -40,6 +41,7 @@ import (
 	"k8s.io/client-go/rest/fake"
 	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
 	"k8s.io/kubectl/pkg/scheme"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestImageLocal(t *testing.T) {
@@ -140,6 +142,39 @@ func TestSetImageValidation(t *testing.T) {
 			},
 			expectErr: "",
 		},
+		{
+			name: "test --local with --preheat",
+			imageOptions: &SetImageOptions{
+				PrintFlags:      printFlags,
+				Resources:       []string{"a"},
+				ContainerImages: map[string]string{"test": "test"},
+				Local:           true,
+				Preheat:         true,
+			},
+			expectErr: "cannot specify --local and --preheat",
+		},
+		{
+			name: "test negative --preheat-timeout",
+			imageOptions: &SetImageOptions{
+				PrintFlags:      printFlags,
+				Resources:       []string{"a"},
+				ContainerImages: map[string]string{"test": "test"},
+				Preheat:         true,
+				PreheatTimeout:  -time.Minute,
+			},
+			expectErr: "--preheat-timeout must not be negative",
+		},
+		{
+			name: "test --preheat with --preheat-timeout",
+			imageOptions: &SetImageOptions{
+				PrintFlags:      printFlags,
+				Resources:       []string{"a"},
+				ContainerImages: map[string]string{"test": "test"},
+				Preheat:         true,
+				PreheatTimeout:  time.Minute,
+			},
+			expectErr: "",
+		},
 	}
 	for _, testCase := range testCases {
 		err := testCase.imageOptions.Validate()
@@ -154,6 +189,64 @@ func TestSetImageValidation(t *testing.T) {
 	}
 }
 
+func TestNodesRunningPods(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "web", UID: "web"},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+	}
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "web-6d4cf56db6", UID: "web-6d4cf56db6",
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind("Deployment"))}},
+	}
+	newPod := func(name, app, nodeName string, owner metav1.Object) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: name, Labels: map[string]string{"app": app}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+		if owner != nil {
+			pod.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))}
+		}
+		return pod
+	}
+	o := &SetImageOptions{PreheatReader: crfake.NewFakeClientWithScheme(scheme.Scheme, replicaSet,
+		newPod("web-a", "web", "node-b", replicaSet), newPod("web-b", "web", "node-a", replicaSet), newPod("web-c", "web", "node-b", replicaSet),
+		newPod("web-d", "web", "", replicaSet), newPod("web-e", "web", "node-d", nil), newPod("db-a", "db", "node-c", nil))}
+
+	// pods matching the selector but not controlled by the deployment are ignored
+	nodes, err := o.nodesRunningPods(&resource.Info{Object: deployment})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node-a", "node-b"}, nodes)
+
+	nodes, err = o.nodesRunningPods(&resource.Info{Object: newPod("db-a", "db", "node-c", nil)})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node-c"}, nodes)
+
+	// unscheduled pods run on no nodes
+	nodes, err = o.nodesRunningPods(&resource.Info{Object: newPod("web-d", "web", "", replicaSet)})
+	assert.NoError(t, err)
+	assert.Empty(t, nodes)
+}
+
+func TestChangedImages(t *testing.T) {
+	orig := &corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "busybox:1.32"}},
+		Containers:     []corev1.Container{{Name: "app", Image: "nginx:1.19"}, {Name: "sidecar", Image: "envoy:1.16"}},
+	}
+
+	spec := orig.DeepCopy()
+	assert.Empty(t, changedImages(orig, spec))
+
+	// images set to their current value are not changed
+	spec.Containers[0].Image = "nginx:1.20"
+	spec.Containers[1].Image = "envoy:1.16"
+	spec.InitContainers[0].Image = "busybox:1.33"
+	assert.Equal(t, []string{"busybox:1.33", "nginx:1.20"}, changedImages(orig, spec))
+
+	// containers updated to the same image preheat it once
+	spec.Containers[1].Image = "nginx:1.20"
+	assert.Equal(t, []string{"busybox:1.33", "nginx:1.20"}, changedImages(orig, spec))
+}
+
 func TestSetMultiResourcesImageLocal(t *testing.T) {
 	tf := cmdtesting.NewTestFactory().WithNamespace("test")
 	defer tf.Cleanup()
@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	sidecarSetLong = templates.LongDesc(i18n.T(`
+		Work with SidecarSets.`))
+
+	sidecarSetExample = templates.Examples(`
+		# Preview the sidecars injected into the pods of a cloneset
+		kubectl-kruise sidecarset preview -f sidecarset.yaml --for=cloneset/foo`)
+)
+
+// NewCmdSidecarSet returns a Command instance for 'sidecarset' sub command
+func NewCmdSidecarSet(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "sidecarset SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Work with SidecarSets"),
+		Long:                  sidecarSetLong,
+		Example:               sidecarSetExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.Out),
+	}
+	// subcommands
+	cmd.AddCommand(NewCmdSidecarSetPreview(f, streams))
+
+	return cmd
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	"fmt"
+	"strings"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	internalapi "github.com/openkruise/kruise-tools/pkg/api"
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	internalsidecarset "github.com/openkruise/kruise-tools/pkg/internal/sidecarset"
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	previewLong = templates.LongDesc(i18n.T(`
+		Preview the injection of SidecarSets into pods without creating or updating anything.
+
+		For each pod or workload, the SidecarSets whose selectors match its pod template are
+		listed, followed by the diff of the pod template after the sidecars are injected,
+		including the shared volumes and transferred env vars. The pods and workloads are read
+		from the server with --for, or from local files with --for-filename.
+
+		Only the containers, volumes and env vars are previewed: the sidecarset hash annotations
+		the webhook adds to pods are left out, and the upgradeStrategy of the sidecar containers,
+		e.g. hot upgrade, is not taken into account. The kruise-api this command is built with has no injectionStrategy,
+		so sidecarsets with injection paused by newer Kruise versions are previewed as injected.`))
+
+	previewExample = templates.Examples(`
+		# Preview the injection of the sidecarset in sidecarset.yaml into the pods of a cloneset
+		kubectl-kruise sidecarset preview -f sidecarset.yaml --for=cloneset/foo
+
+		# Preview the injection into a pod and a deployment
+		kubectl-kruise sidecarset preview -f sidecarset.yaml --for=pod/foo-abcde --for=deployment/bar
+
+		# Preview the injection into the workloads in a local file, without contacting the server
+		kubectl-kruise sidecarset preview -f sidecarset.yaml --for-filename=cloneset.yaml`)
+)
+
+// SidecarSetPreviewOptions is the start of the data required to perform the operation.  As new fields are added, add them here instead of
+// referencing the cmd.Flags()
+type SidecarSetPreviewOptions struct {
+	resource.FilenameOptions
+
+	For          []string
+	ForFilenames []string
+
+	Namespace        string
+	EnforceNamespace bool
+	Builder          func() *resource.Builder
+
+	genericclioptions.IOStreams
+}
+
+// NewSidecarSetPreviewOptions returns an initialized SidecarSetPreviewOptions instance
+func NewSidecarSetPreviewOptions(streams genericclioptions.IOStreams) *SidecarSetPreviewOptions {
+	return &SidecarSetPreviewOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdSidecarSetPreview returns a Command instance for 'sidecarset preview' sub command
+func NewCmdSidecarSetPreview(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewSidecarSetPreviewOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:                   "preview -f FILENAME (--for=TYPE/NAME | --for-filename=FILENAME)",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Preview the injection of SidecarSets into pods"),
+		Long:                  previewLong,
+		Example:               previewExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	usage := "containing the sidecarsets to preview."
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
+	cmd.Flags().StringSliceVar(&o.For, "for", o.For, "The pods or workloads to preview the injection into, e.g. cloneset/foo. They are read from the server.")
+	cmd.Flags().StringSliceVar(&o.ForFilenames, "for-filename", o.ForFilenames, "Files containing the pods or workloads to preview the injection into.")
+	return cmd
+}
+
+// Complete completes all the required options
+func (o *SidecarSetPreviewOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmdutil.UsageErrorf(cmd, "unexpected args: %v", args)
+	}
+
+	var err error
+	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Builder = f.NewBuilder
+	return nil
+}
+
+// Validate makes sure provided values in SidecarSetPreviewOptions are valid
+func (o *SidecarSetPreviewOptions) Validate() error {
+	if cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
+		return fmt.Errorf("the sidecarsets must be given with -f")
+	}
+	if len(o.For) == 0 && len(o.ForFilenames) == 0 {
+		return fmt.Errorf("at least one of --for and --for-filename must be specified")
+	}
+	return nil
+}
+
+// Run performs the execution of 'sidecarset preview' sub command
+func (o *SidecarSetPreviewOptions) Run() error {
+	sidecarSets, err := o.sidecarSets()
+	if err != nil {
+		return err
+	}
+	targets, err := o.targets()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, info := range targets {
+		if i > 0 {
+			fmt.Fprintln(o.Out)
+		}
+		if err := o.preview(sidecarSets, info); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", objectName(info), err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// sidecarSets returns the SidecarSets in the files given by -f, which are read locally.
+func (o *SidecarSetPreviewOptions) sidecarSets() ([]*kruiseappsv1alpha1.SidecarSet, error) {
+	infos, err := o.Builder().
+		WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+		Local().
+		FilenameParam(false, &o.FilenameOptions).
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return nil, err
+	}
+
+	var sidecarSets []*kruiseappsv1alpha1.SidecarSet
+	for _, info := range infos {
+		sidecarSet, ok := info.Object.(*kruiseappsv1alpha1.SidecarSet)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a sidecarset", objectName(info))
+		}
+		sidecarSets = append(sidecarSets, sidecarSet)
+	}
+	if len(sidecarSets) == 0 {
+		return nil, fmt.Errorf("no sidecarsets found in the files given by -f")
+	}
+	return sidecarSets, nil
+}
+
+// targets returns the pods and workloads given by --for from the server, and by --for-filename from local files.
+func (o *SidecarSetPreviewOptions) targets() ([]*resource.Info, error) {
+	var targets []*resource.Info
+	if len(o.ForFilenames) > 0 {
+		infos, err := o.Builder().
+			WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+			Local().
+			NamespaceParam(o.Namespace).DefaultNamespace().
+			FilenameParam(o.EnforceNamespace, &resource.FilenameOptions{Filenames: o.ForFilenames}).
+			Flatten().
+			Do().
+			Infos()
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, infos...)
+	}
+	if len(o.For) > 0 {
+		infos, err := o.Builder().
+			WithScheme(internalapi.GetScheme(), scheme.Scheme.PrioritizedVersionsAllGroups()...).
+			NamespaceParam(o.Namespace).DefaultNamespace().
+			ResourceTypeOrNameArgs(true, o.For...).
+			Latest().
+			Flatten().
+			Do().
+			Infos()
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, infos...)
+	}
+	return targets, nil
+}
+
+// preview prints the SidecarSets matching the pod template of the object in info, and the diff of the template after injection.
+func (o *SidecarSetPreviewOptions) preview(sidecarSets []*kruiseappsv1alpha1.SidecarSet, info *resource.Info) error {
+	template, err := podTemplateForObject(info.Object)
+	if err != nil {
+		return err
+	}
+	namespace := info.Namespace
+	if len(namespace) == 0 {
+		namespace = o.Namespace
+	}
+
+	name := objectName(info)
+	injected := template
+	var matched []string
+	for _, sidecarSet := range sidecarSets {
+		ok, err := internalsidecarset.Matches(sidecarSet, namespace, template.Labels)
+		if err != nil {
+			return fmt.Errorf("invalid selector of sidecarset %s: %v", sidecarSet.Name, err)
+		}
+		if !ok {
+			fmt.Fprintf(o.Out, "sidecarset/%s does not match %s\n", sidecarSet.Name, name)
+			continue
+		}
+		fmt.Fprintf(o.Out, "sidecarset/%s matches %s\n", sidecarSet.Name, name)
+		matched = append(matched, "sidecarset/"+sidecarSet.Name)
+		injected = internalsidecarset.InjectInto(sidecarSet, injected, template.Spec.Containers)
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	diff, err := internalpolymorphichelpers.DiffPodTemplates(name, fmt.Sprintf("%s with %s", name, strings.Join(matched, ", ")), template, injected)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(o.Out, diff)
+	return nil
+}
+
+// podTemplateForObject returns the pod template of the workload, or the metadata and spec of the pod.
+func podTemplateForObject(obj runtime.Object) (*corev1.PodTemplateSpec, error) {
+	switch t := obj.(type) {
+	case *corev1.Pod:
+		return &corev1.PodTemplateSpec{
+			ObjectMeta: *t.ObjectMeta.DeepCopy(),
+			Spec:       *t.Spec.DeepCopy(),
+		}, nil
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		template, err := internalpolymorphichelpers.UnitedDeploymentPodTemplate(t)
+		if err != nil {
+			return nil, err
+		}
+		return template.DeepCopy(), nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	templateContent, found, err := unstructured.NestedMap(content, "spec", "template")
+	if err != nil || !found {
+		return nil, fmt.Errorf("no pod template found")
+	}
+	template := &corev1.PodTemplateSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(templateContent, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// objectName returns the name of the object in info in the form kind/name, e.g. cloneset/foo.
+// Local objects have no mapping, so their kind is looked up in the scheme.
+func objectName(info *resource.Info) string {
+	if info.Mapping != nil {
+		return fmt.Sprintf("%s/%s", strings.ToLower(info.Mapping.GroupVersionKind.Kind), info.Name)
+	}
+	if gvks, _, err := internalapi.GetScheme().ObjectKinds(info.Object); err == nil && len(gvks) > 0 {
+		return fmt.Sprintf("%s/%s", strings.ToLower(gvks[0].Kind), info.Name)
+	}
+	return info.Name
+}
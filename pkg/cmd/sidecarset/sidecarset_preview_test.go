@@ -0,0 +1,144 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	"strings"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+func testPodTemplate() corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/var/log/app"}}},
+		}},
+	}
+}
+
+func TestPodTemplateForObject(t *testing.T) {
+	template := testPodTemplate()
+	tests := []struct {
+		name      string
+		obj       runtime.Object
+		expectErr bool
+	}{
+		{
+			name: "pod",
+			obj:  &corev1.Pod{ObjectMeta: template.ObjectMeta, Spec: template.Spec},
+		},
+		{
+			name: "cloneset",
+			obj:  &kruiseappsv1alpha1.CloneSet{Spec: kruiseappsv1alpha1.CloneSetSpec{Template: template}},
+		},
+		{
+			name: "uniteddeployment",
+			obj: &kruiseappsv1alpha1.UnitedDeployment{Spec: kruiseappsv1alpha1.UnitedDeploymentSpec{
+				Template: kruiseappsv1alpha1.SubsetTemplate{CloneSetTemplate: &kruiseappsv1alpha1.CloneSetTemplateSpec{
+					Spec: kruiseappsv1alpha1.CloneSetSpec{Template: template},
+				}},
+			}},
+		},
+		{
+			name:      "uniteddeployment without subset template",
+			obj:       &kruiseappsv1alpha1.UnitedDeployment{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+			expectErr: true,
+		},
+		{
+			name:      "configmap",
+			obj:       &corev1.ConfigMap{},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := podTemplateForObject(test.obj)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, &template, got)
+		})
+	}
+}
+
+func TestPreview(t *testing.T) {
+	newSidecarSet := func(name, app string, containers ...kruiseappsv1alpha1.SidecarContainer) *kruiseappsv1alpha1.SidecarSet {
+		return &kruiseappsv1alpha1.SidecarSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: kruiseappsv1alpha1.SidecarSetSpec{
+				Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+				Containers: containers,
+			},
+		}
+	}
+	sidecarSets := []*kruiseappsv1alpha1.SidecarSet{
+		newSidecarSet("mesh", "web", kruiseappsv1alpha1.SidecarContainer{
+			Container: corev1.Container{Name: "proxy", VolumeMounts: []corev1.VolumeMount{{Name: "proxy-conf", MountPath: "/etc/proxy"}}},
+		}),
+		newSidecarSet("logging", "web", kruiseappsv1alpha1.SidecarContainer{
+			Container:         corev1.Container{Name: "logger"},
+			ShareVolumePolicy: kruiseappsv1alpha1.ShareVolumePolicy{Type: kruiseappsv1alpha1.ShareVolumePolicyEnabled},
+		}),
+		newSidecarSet("db-agent", "db", kruiseappsv1alpha1.SidecarContainer{Container: corev1.Container{Name: "agent"}}),
+	}
+	info := &resource.Info{
+		Namespace: "default",
+		Name:      "web",
+		Object:    &kruiseappsv1alpha1.CloneSet{Spec: kruiseappsv1alpha1.CloneSetSpec{Template: testPodTemplate()}},
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewSidecarSetPreviewOptions(streams)
+	assert.NoError(t, o.preview(sidecarSets, info))
+
+	output := out.String()
+	for _, expected := range []string{
+		"sidecarset/mesh matches cloneset/web\n",
+		"sidecarset/logging matches cloneset/web\n",
+		"sidecarset/db-agent does not match cloneset/web\n",
+		"+++ cloneset/web with sidecarset/mesh, sidecarset/logging\n",
+		"+  - name: proxy\n",
+		"+  - name: logger\n",
+	} {
+		assert.Contains(t, output, expected)
+	}
+	// the logger shares the volumes of the app, but not those of the proxy injected before it
+	logger := output[strings.Index(output, "+  - name: logger"):]
+	assert.Contains(t, logger, "mountPath: /var/log/app")
+	assert.NotContains(t, logger, "mountPath: /etc/proxy")
+}
+
+func TestValidate(t *testing.T) {
+	o := &SidecarSetPreviewOptions{}
+	assert.EqualError(t, o.Validate(), "the sidecarsets must be given with -f")
+
+	o.Filenames = []string{"sidecarset.yaml"}
+	assert.EqualError(t, o.Validate(), "at least one of --for and --for-filename must be specified")
+
+	o.For = []string{"cloneset/web"}
+	assert.NoError(t, o.Validate())
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/kubectl/pkg/util/term"
+)
+
+// TableRow is a row of a LiveTable.
+type TableRow struct {
+	// Key identifies the row across renderings.
+	Key   string
+	Cells []string
+}
+
+// LiveTable renders rows as a table, redrawn in place on a terminal.
+// Otherwise only the rows changed since the last rendering are printed.
+type LiveTable struct {
+	out      io.Writer
+	header   []string
+	terminal bool
+	lines    int
+	printed  map[string]string
+}
+
+// NewLiveTable returns a LiveTable with the header which renders to out.
+func NewLiveTable(out io.Writer, header ...string) *LiveTable {
+	return &LiveTable{
+		out:      out,
+		header:   header,
+		terminal: term.TTY{Out: out}.IsTerminalOut(),
+		printed:  map[string]string{},
+	}
+}
+
+// Render prints the rows, replacing the previous rendering on a terminal.
+func (t *LiveTable) Render(rows []TableRow) {
+	buf := &bytes.Buffer{}
+	w := printers.GetNewTabWriter(buf)
+	if t.terminal || len(t.printed) == 0 {
+		fmt.Fprintln(w, strings.Join(t.header, "\t"))
+	}
+	for _, r := range rows {
+		row := strings.Join(r.Cells, "\t")
+		if !t.terminal {
+			if t.printed[r.Key] == row {
+				continue
+			}
+			t.printed[r.Key] = row
+		}
+		fmt.Fprintln(w, row)
+	}
+	w.Flush()
+
+	if t.terminal && t.lines > 0 {
+		// move the cursor back to the top of the last table and clear it
+		fmt.Fprintf(t.out, "\x1b[%dA\x1b[J", t.lines)
+	}
+	t.lines = strings.Count(buf.String(), "\n")
+	t.out.Write(buf.Bytes())
+}
+
+// ValueOrNone returns the value for a table cell, or "<none>" if it is empty.
+func ValueOrNone(value string) string {
+	if len(value) == 0 {
+		return "<none>"
+	}
+	return value
+}
+
+// FirstLine returns the first line of a message to fit a table cell.
+func FirstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	table := NewLiveTable(out, "NAME", "STATUS")
+
+	table.Render([]TableRow{
+		{Key: "a", Cells: []string{"a", "Running"}},
+		{Key: "b", Cells: []string{"b", "Running"}},
+	})
+	assert.Equal(t, "NAME   STATUS\na      Running\nb      Running\n", out.String())
+
+	// only the changed rows are printed when out is not a terminal
+	out.Reset()
+	table.Render([]TableRow{
+		{Key: "a", Cells: []string{"a", "Running"}},
+		{Key: "b", Cells: []string{"b", "Done"}},
+	})
+	assert.Equal(t, "b     Done\n", out.String())
+
+	out.Reset()
+	table.Render([]TableRow{
+		{Key: "a", Cells: []string{"a", "Running"}},
+		{Key: "b", Cells: []string{"b", "Done"}},
+	})
+	assert.Empty(t, out.String())
+}
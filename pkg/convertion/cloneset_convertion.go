@@ -19,6 +19,7 @@ package convertion
 import (
 	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -59,3 +60,64 @@ func DeploymentToCloneSet(deploy *apps.Deployment) *appsv1alpha1.CloneSet {
 	}
 	return cs
 }
+
+// Convert ReplicaSet to CloneSet
+func ReplicaSetToCloneSet(rs *apps.ReplicaSet) *appsv1alpha1.CloneSet {
+	// Deep copy first
+	from := rs.DeepCopy()
+
+	return &appsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   from.Namespace,
+			Name:        from.Name,
+			Labels:      from.Labels,
+			Annotations: from.Annotations,
+			Finalizers:  from.Finalizers,
+			ClusterName: from.ClusterName,
+		},
+		Spec: appsv1alpha1.CloneSetSpec{
+			Replicas:        from.Spec.Replicas,
+			Selector:        from.Spec.Selector,
+			Template:        from.Spec.Template,
+			MinReadySeconds: from.Spec.MinReadySeconds,
+			UpdateStrategy: appsv1alpha1.CloneSetUpdateStrategy{
+				Type: appsv1alpha1.RecreateCloneSetUpdateStrategyType,
+			},
+		},
+	}
+}
+
+// Convert ReplicationController to CloneSet
+func ReplicationControllerToCloneSet(rc *corev1.ReplicationController) *appsv1alpha1.CloneSet {
+	// Deep copy first
+	from := rc.DeepCopy()
+
+	cs := &appsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   from.Namespace,
+			Name:        from.Name,
+			Labels:      from.Labels,
+			Annotations: from.Annotations,
+			Finalizers:  from.Finalizers,
+			ClusterName: from.ClusterName,
+		},
+		Spec: appsv1alpha1.CloneSetSpec{
+			Replicas:        from.Spec.Replicas,
+			MinReadySeconds: from.Spec.MinReadySeconds,
+			UpdateStrategy: appsv1alpha1.CloneSetUpdateStrategy{
+				Type: appsv1alpha1.RecreateCloneSetUpdateStrategyType,
+			},
+		},
+	}
+	if from.Spec.Template != nil {
+		cs.Spec.Template = *from.Spec.Template
+	}
+
+	// selector of ReplicationController defaults to the labels of pod template
+	selector := from.Spec.Selector
+	if len(selector) == 0 {
+		selector = cs.Spec.Template.Labels
+	}
+	cs.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+	return cs
+}
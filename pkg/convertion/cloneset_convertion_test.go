@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package convertion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReplicaSetToCloneSet(t *testing.T) {
+	replicas := int32(3)
+	rs := &apps.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx", Labels: map[string]string{"app": "nginx"}},
+		Spec: apps.ReplicaSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "nginx"}}},
+		},
+	}
+
+	cs := ReplicaSetToCloneSet(rs)
+	assert.Equal(t, "nginx", cs.Name)
+	assert.Equal(t, int32(3), *cs.Spec.Replicas)
+	assert.Equal(t, rs.Spec.Selector, cs.Spec.Selector)
+	assert.Equal(t, rs.Spec.Template, cs.Spec.Template)
+}
+
+func TestReplicationControllerToCloneSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector map[string]string
+		expected map[string]string
+	}{
+		{
+			name:     "explicit selector",
+			selector: map[string]string{"app": "nginx"},
+			expected: map[string]string{"app": "nginx"},
+		},
+		{
+			name:     "selector defaults to template labels",
+			expected: map[string]string{"app": "nginx", "tier": "web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &corev1.ReplicationController{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx"},
+				Spec: corev1.ReplicationControllerSpec{
+					Selector: tt.selector,
+					Template: &corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "nginx", "tier": "web"}}},
+				},
+			}
+
+			cs := ReplicationControllerToCloneSet(rc)
+			assert.Equal(t, &metav1.LabelSelector{MatchLabels: tt.expected}, cs.Spec.Selector)
+			assert.Equal(t, *rc.Spec.Template, cs.Spec.Template)
+		})
+	}
+}
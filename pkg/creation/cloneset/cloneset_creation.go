@@ -24,8 +24,11 @@ import (
 	"github.com/openkruise/kruise-tools/pkg/api"
 	"github.com/openkruise/kruise-tools/pkg/convertion"
 	"github.com/openkruise/kruise-tools/pkg/creation"
+	"github.com/openkruise/kruise-tools/pkg/migration"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
@@ -51,30 +54,54 @@ func NewControl(cfg *rest.Config) (creation.Control, error) {
 }
 
 func (c *control) Create(src api.ResourceRef, dst api.ResourceRef, opts creation.Options) error {
-	if src.GetGroupVersionKind() != api.DeploymentKind {
-		return fmt.Errorf("invalid src type, currently only support %v", api.DeploymentKind.String())
-	} else if dst.GetGroupVersionKind() != api.CloneSetKind {
+	if dst.GetGroupVersionKind() != api.CloneSetKind {
 		return fmt.Errorf("invalid dst type, must be %v", api.CloneSetKind.String())
 	}
 
 	if err := c.ensureCloneSetNotExists(dst); err != nil {
 		return err
 	}
-	srcDeployment, err := c.getDeployment(src)
-	if err != nil {
-		return err
+
+	var dstCloneSet *appsv1alpha1.CloneSet
+	switch src.GetGroupVersionKind() {
+	case api.DeploymentKind:
+		d := &apps.Deployment{}
+		if err := c.getObject(src, d); err != nil {
+			return err
+		}
+		dstCloneSet = convertion.DeploymentToCloneSet(d)
+	case api.ReplicaSetKind:
+		rs := &apps.ReplicaSet{}
+		if err := c.getObject(src, rs); err != nil {
+			return err
+		}
+		dstCloneSet = convertion.ReplicaSetToCloneSet(rs)
+	case api.ReplicationControllerKind:
+		rc := &corev1.ReplicationController{}
+		if err := c.getObject(src, rc); err != nil {
+			return err
+		}
+		dstCloneSet = convertion.ReplicationControllerToCloneSet(rc)
+	default:
+		return fmt.Errorf("invalid src type, currently only support %v, %v and %v",
+			api.DeploymentKind.String(), api.ReplicaSetKind.String(), api.ReplicationControllerKind.String())
 	}
 
-	dstCloneSet := convertion.DeploymentToCloneSet(srcDeployment)
+	// dst may be in another namespace or with another name
+	dstCloneSet.Namespace = dst.Namespace
+	if len(dst.Name) > 0 {
+		dstCloneSet.Name = dst.Name
+	}
+	// the lock held on src by a running migration does not belong to the new dst
+	migration.RemoveLock(dstCloneSet)
 	return c.client.Create(context.TODO(), dstCloneSet)
 }
 
-func (c *control) getDeployment(ref api.ResourceRef) (*apps.Deployment, error) {
-	d := &apps.Deployment{}
-	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), d); err != nil {
-		return nil, fmt.Errorf("failed to get %v: %v", ref, err)
+func (c *control) getObject(ref api.ResourceRef, obj runtime.Object) error {
+	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), obj); err != nil {
+		return fmt.Errorf("failed to get %v: %v", ref, err)
 	}
-	return d, nil
+	return nil
 }
 
 func (c *control) ensureCloneSetNotExists(ref api.ResourceRef) error {
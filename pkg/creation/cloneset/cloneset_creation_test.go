@@ -0,0 +1,53 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloneset
+
+import (
+	"context"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/creation"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCreateInAnotherNamespace(t *testing.T) {
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "legacy",
+			Name:        "web",
+			Annotations: map[string]string{"owner": "team-a", migration.LockAnnotation: `{"holder":"someone"}`},
+		},
+	}
+	c := &control{client: fake.NewFakeClientWithScheme(api.GetScheme(), deployment)}
+
+	err := c.Create(api.NewDeploymentRef("legacy", "web"), api.NewCloneSetRef("apps", "web-cs"), creation.Options{})
+	assert.NoError(t, err)
+
+	cs := &appsv1alpha1.CloneSet{}
+	assert.NoError(t, c.client.Get(context.TODO(), types.NamespacedName{Namespace: "apps", Name: "web-cs"}, cs))
+	assert.Equal(t, map[string]string{"owner": "team-a"}, cs.Annotations)
+
+	err = c.Create(api.NewDeploymentRef("legacy", "web"), api.NewCloneSetRef("apps", "web-cs"), creation.Options{})
+	assert.Error(t, err)
+}
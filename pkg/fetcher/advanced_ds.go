@@ -0,0 +1,15 @@
+package fetcher
+
+import (
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func GetAdvancedDaemonSetInCache(ns, name string, cl client.Reader) (*kruiseappsv1alpha1.DaemonSet, bool, error) {
+	ds := &kruiseappsv1alpha1.DaemonSet{}
+	found, err := GetResourceInCache(ns, name, ds, cl)
+	if err != nil || !found {
+		ds = nil
+	}
+	return ds, found, err
+}
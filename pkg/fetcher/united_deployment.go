@@ -0,0 +1,15 @@
+package fetcher
+
+import (
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func GetUnitedDeploymentInCache(ns, name string, cl client.Reader) (*kruiseappsv1alpha1.UnitedDeployment, bool, error) {
+	ud := &kruiseappsv1alpha1.UnitedDeployment{}
+	found, err := GetResourceInCache(ns, name, ud, cl)
+	if err != nil || !found {
+		ud = nil
+	}
+	return ud, found, err
+}
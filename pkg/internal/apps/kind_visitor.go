@@ -36,6 +36,8 @@ type KindVisitor interface {
 	VisitCronJob(kind GroupKindElement)
 	VisitCloneSet(kind GroupKindElement)
 	VisitAdvancedStatefulSet(kind GroupKindElement)
+	VisitAdvancedDaemonSet(kind GroupKindElement)
+	VisitUnitedDeployment(kind GroupKindElement)
 }
 
 // GroupKindElement defines a Kubernetes API group elem
@@ -64,6 +66,10 @@ func (elem GroupKindElement) Accept(visitor KindVisitor) error {
 		visitor.VisitCloneSet(elem)
 	case elem.GroupMatch("apps.kruise.io") && elem.Kind == "StatefulSet":
 		visitor.VisitAdvancedStatefulSet(elem)
+	case elem.GroupMatch("apps.kruise.io") && elem.Kind == "DaemonSet":
+		visitor.VisitAdvancedDaemonSet(elem)
+	case elem.GroupMatch("apps.kruise.io") && elem.Kind == "UnitedDeployment":
+		visitor.VisitUnitedDeployment(elem)
 	default:
 		return fmt.Errorf("no visitor method exists for %v", elem)
 	}
@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerrecreate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/internal/poll"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ttlSecondsAfterFinished has kruise delete the completed requests, as every restart leaves one request per pod.
+	ttlSecondsAfterFinished int32 = 600
+
+	pollInterval = time.Second
+)
+
+// NewRequest returns a ContainerRecreateRequest which recreates the given containers of the pod,
+// or all the containers of the pod if none is given.
+func NewRequest(pod *corev1.Pod, containers []string, strategy *kruiseappsv1alpha1.ContainerRecreateRequestStrategy) (*kruiseappsv1alpha1.ContainerRecreateRequest, error) {
+	if len(containers) == 0 {
+		for _, c := range pod.Spec.Containers {
+			containers = append(containers, c.Name)
+		}
+	}
+
+	var recreateContainers []kruiseappsv1alpha1.ContainerRecreateRequestContainer
+	for _, name := range containers {
+		found := false
+		for _, c := range pod.Spec.Containers {
+			if c.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("container %q not found in pod %s", name, pod.Name)
+		}
+		recreateContainers = append(recreateContainers, kruiseappsv1alpha1.ContainerRecreateRequestContainer{Name: name})
+	}
+
+	ttl := ttlSecondsAfterFinished
+	return &kruiseappsv1alpha1.ContainerRecreateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    pod.Namespace,
+			GenerateName: pod.Name + "-",
+		},
+		Spec: kruiseappsv1alpha1.ContainerRecreateRequestSpec{
+			PodName:                 pod.Name,
+			Containers:              recreateContainers,
+			Strategy:                strategy,
+			TTLSecondsAfterFinished: &ttl,
+		},
+	}, nil
+}
+
+// Wait polls the ContainerRecreateRequest until it is completed or ctx is done.
+// onUpdate, if not nil, is called whenever the phase of the request or any of its containers changes.
+func Wait(ctx context.Context, c client.Reader, crr *kruiseappsv1alpha1.ContainerRecreateRequest, onUpdate func(*kruiseappsv1alpha1.ContainerRecreateRequest)) (*kruiseappsv1alpha1.ContainerRecreateRequest, error) {
+	var notify func(runtime.Object)
+	if onUpdate != nil {
+		notify = func(obj runtime.Object) { onUpdate(obj.(*kruiseappsv1alpha1.ContainerRecreateRequest)) }
+	}
+	obj, err := poll.UntilCompleted(ctx, c, crr, pollInterval,
+		func(obj runtime.Object) bool { return IsCompleted(obj.(*kruiseappsv1alpha1.ContainerRecreateRequest)) },
+		func(obj runtime.Object) interface{} {
+			// the message is left out, which may change without the request making any progress
+			status := obj.(*kruiseappsv1alpha1.ContainerRecreateRequest).Status
+			return kruiseappsv1alpha1.ContainerRecreateRequestStatus{Phase: status.Phase, ContainerRecreateStates: status.ContainerRecreateStates}
+		},
+		notify)
+	crr = obj.(*kruiseappsv1alpha1.ContainerRecreateRequest)
+	if err == wait.ErrWaitTimeout {
+		err = fmt.Errorf("timed out waiting for ContainerRecreateRequest %s", crr.Name)
+	}
+	return crr, err
+}
+
+// IsCompleted returns whether the ContainerRecreateRequest has finished, successfully or not.
+func IsCompleted(crr *kruiseappsv1alpha1.ContainerRecreateRequest) bool {
+	return crr.Status.Phase == kruiseappsv1alpha1.ContainerRecreateRequestCompleted
+}
+
+// Failures returns the reasons why the completed ContainerRecreateRequest did not recreate all its containers.
+func Failures(crr *kruiseappsv1alpha1.ContainerRecreateRequest) []string {
+	var failures []string
+	succeeded := 0
+	for _, state := range crr.Status.ContainerRecreateStates {
+		if state.Phase == kruiseappsv1alpha1.ContainerRecreateRequestSucceeded {
+			succeeded++
+			continue
+		}
+		failure := fmt.Sprintf("container %s %s", state.Name, state.Phase)
+		if len(state.Message) > 0 {
+			failure += ": " + state.Message
+		}
+		failures = append(failures, failure)
+	}
+	// the request can be completed before any container is recreated, e.g. the pod has gone or the deadline exceeded
+	if len(failures) == 0 && succeeded < len(crr.Spec.Containers) {
+		failure := fmt.Sprintf("%d of %d containers recreated", succeeded, len(crr.Spec.Containers))
+		if len(crr.Status.Message) > 0 {
+			failure += ": " + crr.Status.Message
+		}
+		failures = append(failures, failure)
+	}
+	return failures
+}
+
+// RecreatePods recreates the containers of the pods through ContainerRecreateRequests, at most concurrency pods
+// at a time, and waits for the requests to complete. No more pods are started once any of them fails or ctx is done.
+// It returns the last request seen for each pod, or nil for the pods never started.
+// onUpdate, if not nil, is called one at a time whenever the request of a pod changes.
+func RecreatePods(ctx context.Context, c client.Client, r client.Reader, pods []*corev1.Pod, containers []string,
+	strategy *kruiseappsv1alpha1.ContainerRecreateRequestStrategy, concurrency int,
+	onUpdate func(*corev1.Pod, *kruiseappsv1alpha1.ContainerRecreateRequest)) ([]*kruiseappsv1alpha1.ContainerRecreateRequest, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errs    []error
+		skipped int
+		results = make([]*kruiseappsv1alpha1.ContainerRecreateRequest, len(pods))
+	)
+	tokens := make(chan struct{}, concurrency)
+	for i, pod := range pods {
+		// a failed pod releases its token after its error is recorded, so it is seen once a token is taken
+		select {
+		case tokens <- struct{}{}:
+		case <-ctx.Done():
+		}
+		mu.Lock()
+		failed := len(errs) > 0
+		mu.Unlock()
+		if failed || ctx.Err() != nil {
+			skipped = len(pods) - i
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, pod *corev1.Pod) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+			crr, err := recreatePod(ctx, c, r, pod, containers, strategy, func(crr *kruiseappsv1alpha1.ContainerRecreateRequest) {
+				if onUpdate != nil {
+					mu.Lock()
+					defer mu.Unlock()
+					onUpdate(pod, crr)
+				}
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[i] = crr
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pod %s: %v", pod.Name, err))
+			}
+		}(i, pod)
+	}
+	wg.Wait()
+
+	if skipped > 0 {
+		errs = append(errs, fmt.Errorf("%d pods are skipped", skipped))
+	}
+	return results, utilerrors.NewAggregate(errs)
+}
+
+// recreatePod creates the request for the pod and waits for it to complete.
+func recreatePod(ctx context.Context, c client.Client, r client.Reader, pod *corev1.Pod, containers []string,
+	strategy *kruiseappsv1alpha1.ContainerRecreateRequestStrategy, onUpdate func(*kruiseappsv1alpha1.ContainerRecreateRequest)) (*kruiseappsv1alpha1.ContainerRecreateRequest, error) {
+	crr, err := NewRequest(pod, containers, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Create(ctx, crr); err != nil {
+		return nil, err
+	}
+	klog.V(4).Infof("created ContainerRecreateRequest %s for pod %s", crr.Name, pod.Name)
+
+	crr, err = Wait(ctx, r, crr, onUpdate)
+	if err != nil {
+		return crr, err
+	}
+	if failures := Failures(crr); len(failures) > 0 {
+		return crr, errors.New(strings.Join(failures, "; "))
+	}
+	return crr, nil
+}
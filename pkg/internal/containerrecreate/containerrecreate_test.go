@@ -0,0 +1,198 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containerrecreate
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewRequest(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo-abcde"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}},
+	}
+
+	crr, err := NewRequest(pod, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "default", crr.Namespace)
+	assert.Equal(t, "foo-abcde-", crr.GenerateName)
+	assert.Equal(t, "foo-abcde", crr.Spec.PodName)
+	assert.Equal(t, []kruiseappsv1alpha1.ContainerRecreateRequestContainer{{Name: "app"}, {Name: "sidecar"}}, crr.Spec.Containers)
+
+	crr, err = NewRequest(pod, []string{"sidecar"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []kruiseappsv1alpha1.ContainerRecreateRequestContainer{{Name: "sidecar"}}, crr.Spec.Containers)
+
+	_, err = NewRequest(pod, []string{"app", "unknown"}, nil)
+	assert.Error(t, err)
+}
+
+func TestFailures(t *testing.T) {
+	crr := &kruiseappsv1alpha1.ContainerRecreateRequest{
+		Spec: kruiseappsv1alpha1.ContainerRecreateRequestSpec{
+			Containers: []kruiseappsv1alpha1.ContainerRecreateRequestContainer{{Name: "app"}, {Name: "sidecar"}},
+		},
+		Status: kruiseappsv1alpha1.ContainerRecreateRequestStatus{
+			Phase: kruiseappsv1alpha1.ContainerRecreateRequestCompleted,
+			ContainerRecreateStates: []kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState{
+				{Name: "app", Phase: kruiseappsv1alpha1.ContainerRecreateRequestSucceeded},
+				{Name: "sidecar", Phase: kruiseappsv1alpha1.ContainerRecreateRequestSucceeded},
+			},
+		},
+	}
+	assert.Empty(t, Failures(crr))
+
+	crr.Status.ContainerRecreateStates[1] = kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState{
+		Name: "sidecar", Phase: kruiseappsv1alpha1.ContainerRecreateRequestFailed, Message: "failed to kill container",
+	}
+	assert.Equal(t, []string{"container sidecar Failed: failed to kill container"}, Failures(crr))
+
+	crr.Status.ContainerRecreateStates = nil
+	crr.Status.Message = "pod has changed"
+	assert.Equal(t, []string{"0 of 2 containers recreated: pod has changed"}, Failures(crr))
+}
+
+// completingClient completes the created requests after a while, failing the containers of failPod,
+// and records the most requests being created at a time.
+type completingClient struct {
+	client.Client
+	failPod string
+
+	mu        sync.Mutex
+	active    int
+	maxActive int
+	created   []string
+}
+
+func (c *completingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	crr := obj.(*kruiseappsv1alpha1.ContainerRecreateRequest)
+	c.mu.Lock()
+	c.active++
+	if c.active > c.maxActive {
+		c.maxActive = c.active
+	}
+	c.created = append(c.created, crr.Spec.PodName)
+	c.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+	crr.Name = crr.GenerateName + "crr"
+	phase := kruiseappsv1alpha1.ContainerRecreateRequestSucceeded
+	if crr.Spec.PodName == c.failPod {
+		phase = kruiseappsv1alpha1.ContainerRecreateRequestFailed
+	}
+	crr.Status.Phase = kruiseappsv1alpha1.ContainerRecreateRequestCompleted
+	for _, container := range crr.Spec.Containers {
+		crr.Status.ContainerRecreateStates = append(crr.Status.ContainerRecreateStates,
+			kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState{Name: container.Name, Phase: phase})
+	}
+
+	c.mu.Lock()
+	c.active--
+	c.mu.Unlock()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestRecreatePods(t *testing.T) {
+	newPods := func(names ...string) []*corev1.Pod {
+		var pods []*corev1.Pod
+		for _, name := range names {
+			pods = append(pods, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			})
+		}
+		return pods
+	}
+
+	tests := []struct {
+		name          string
+		pods          []*corev1.Pod
+		concurrency   int
+		failPod       string
+		cancelled     bool
+		expectCreated []string
+		expectResults []bool
+		expectErr     string
+	}{
+		{
+			name:          "at most concurrency pods at a time",
+			pods:          newPods("a", "b", "c", "d", "e"),
+			concurrency:   2,
+			expectCreated: []string{"a", "b", "c", "d", "e"},
+			expectResults: []bool{true, true, true, true, true},
+		},
+		{
+			name:          "stop on the first failure",
+			pods:          newPods("a", "b", "c", "d"),
+			concurrency:   1,
+			failPod:       "b",
+			expectCreated: []string{"a", "b"},
+			expectResults: []bool{true, true, false, false},
+			expectErr:     "[pod b: container app Failed, 2 pods are skipped]",
+		},
+		{
+			name:          "no pods once ctx is done",
+			pods:          newPods("a", "b"),
+			concurrency:   1,
+			cancelled:     true,
+			expectResults: []bool{false, false},
+			expectErr:     "2 pods are skipped",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &completingClient{Client: fake.NewFakeClientWithScheme(api.GetScheme()), failPod: test.failPod}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if test.cancelled {
+				cancel()
+			}
+			var updated []string
+			results, err := RecreatePods(ctx, c, c, test.pods, nil, nil, test.concurrency,
+				func(pod *corev1.Pod, crr *kruiseappsv1alpha1.ContainerRecreateRequest) {
+					updated = append(updated, pod.Name)
+				})
+			if len(test.expectErr) > 0 {
+				assert.EqualError(t, err, test.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.ElementsMatch(t, test.expectCreated, c.created)
+			assert.ElementsMatch(t, test.expectCreated, updated)
+			if len(test.expectCreated) > 0 {
+				assert.Equal(t, test.concurrency, c.maxActive)
+			}
+			for i, created := range test.expectResults {
+				assert.Equal(t, created, results[i] != nil, "result of pod %s", test.pods[i].Name)
+			}
+		})
+	}
+}
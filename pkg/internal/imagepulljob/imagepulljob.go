@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagepulljob
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/internal/poll"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ttlSecondsAfterFinished keeps the finished jobs for a while for inspection,
+	// rather than leaving them around forever.
+	ttlSecondsAfterFinished int32 = 600
+
+	pollInterval = 2 * time.Second
+)
+
+// NewJob returns an ImagePullJob which pulls the image on the nodes matching the selector,
+// or on all the nodes if selector is nil. A zero timeout means the job never times out.
+func NewJob(namespace, image string, selector *kruiseappsv1alpha1.ImagePullJobNodeSelector, parallelism *intstr.IntOrString, timeout time.Duration) *kruiseappsv1alpha1.ImagePullJob {
+	ttl := ttlSecondsAfterFinished
+	job := &kruiseappsv1alpha1.ImagePullJob{
+		TypeMeta: metav1.TypeMeta{APIVersion: kruiseappsv1alpha1.SchemeGroupVersion.String(), Kind: "ImagePullJob"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    namespace,
+			GenerateName: "preheat-",
+		},
+		Spec: kruiseappsv1alpha1.ImagePullJobSpec{
+			Image:       image,
+			Selector:    selector,
+			Parallelism: parallelism,
+			CompletionPolicy: kruiseappsv1alpha1.CompletionPolicy{
+				Type:                    kruiseappsv1alpha1.Always,
+				TTLSecondsAfterFinished: &ttl,
+			},
+		},
+	}
+	if timeout > 0 {
+		deadline := int64(timeout.Seconds())
+		job.Spec.CompletionPolicy.ActiveDeadlineSeconds = &deadline
+	}
+	return job
+}
+
+// Wait polls the ImagePullJob until it is completed or ctx is done.
+// onUpdate, if not nil, is called whenever the status of the job changes.
+func Wait(ctx context.Context, c client.Reader, job *kruiseappsv1alpha1.ImagePullJob, onUpdate func(*kruiseappsv1alpha1.ImagePullJob)) (*kruiseappsv1alpha1.ImagePullJob, error) {
+	var notify func(runtime.Object)
+	if onUpdate != nil {
+		notify = func(obj runtime.Object) { onUpdate(obj.(*kruiseappsv1alpha1.ImagePullJob)) }
+	}
+	obj, err := poll.UntilCompleted(ctx, c, job, pollInterval,
+		func(obj runtime.Object) bool { return IsCompleted(obj.(*kruiseappsv1alpha1.ImagePullJob)) },
+		func(obj runtime.Object) interface{} { return obj.(*kruiseappsv1alpha1.ImagePullJob).Status },
+		notify)
+	job = obj.(*kruiseappsv1alpha1.ImagePullJob)
+	if err == wait.ErrWaitTimeout {
+		err = fmt.Errorf("timed out waiting for ImagePullJob %s: %s", job.Name, Progress(job))
+	}
+	return job, err
+}
+
+// IsCompleted returns whether the ImagePullJob has finished, successfully or not.
+func IsCompleted(job *kruiseappsv1alpha1.ImagePullJob) bool {
+	return job.Status.CompletionTime != nil
+}
+
+// Progress describes how many nodes the ImagePullJob has pulled the image on.
+func Progress(job *kruiseappsv1alpha1.ImagePullJob) string {
+	return fmt.Sprintf("%d succeeded, %d failed, %d active of %d nodes",
+		job.Status.Succeeded, job.Status.Failed, job.Status.Active, job.Status.Desired)
+}
+
+// ImagesForPodSpec returns the distinct images of the init containers and containers of the pod spec, in order.
+func ImagesForPodSpec(spec *corev1.PodSpec) []string {
+	var images []string
+	seen := map[string]bool{}
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, c := range containers {
+			if len(c.Image) > 0 && !seen[c.Image] {
+				seen[c.Image] = true
+				images = append(images, c.Image)
+			}
+		}
+	}
+	return images
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagepulljob
+
+import (
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestNewJob(t *testing.T) {
+	job := NewJob("default", "nginx:1.21", nil, nil, 0)
+	assert.Equal(t, "default", job.Namespace)
+	assert.Equal(t, "preheat-", job.GenerateName)
+	assert.Equal(t, "nginx:1.21", job.Spec.Image)
+	assert.Nil(t, job.Spec.Selector)
+	assert.Nil(t, job.Spec.Parallelism)
+	assert.Equal(t, kruiseappsv1alpha1.Always, job.Spec.CompletionPolicy.Type)
+	assert.Nil(t, job.Spec.CompletionPolicy.ActiveDeadlineSeconds)
+
+	selector := &kruiseappsv1alpha1.ImagePullJobNodeSelector{Names: []string{"node-a", "node-b"}}
+	parallelism := intstr.FromInt(2)
+	job = NewJob("default", "nginx:1.21", selector, &parallelism, 10*time.Minute)
+	assert.Equal(t, selector, job.Spec.Selector)
+	assert.Equal(t, &parallelism, job.Spec.Parallelism)
+	assert.Equal(t, int64(600), *job.Spec.CompletionPolicy.ActiveDeadlineSeconds)
+}
+
+func TestIsCompleted(t *testing.T) {
+	job := &kruiseappsv1alpha1.ImagePullJob{}
+	assert.False(t, IsCompleted(job))
+	now := metav1.Now()
+	job.Status.CompletionTime = &now
+	assert.True(t, IsCompleted(job))
+}
+
+func TestImagesForPodSpec(t *testing.T) {
+	spec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+		Containers: []corev1.Container{
+			{Name: "app", Image: "app:v2"},
+			{Name: "sidecar", Image: "busybox"},
+			{Name: "proxy", Image: "envoy:v1"},
+		},
+	}
+	assert.Equal(t, []string{"busybox", "app:v2", "envoy:v1"}, ImagesForPodSpec(spec))
+	assert.Empty(t, ImagesForPodSpec(&corev1.PodSpec{}))
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poll
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// UntilCompleted gets obj every interval until completed returns true for it or ctx is done, in which case
+// wait.ErrWaitTimeout is returned. It returns the object last got, or obj if none has been got.
+// onUpdate, if not nil, is called with the object whenever what progress returns for it changes.
+func UntilCompleted(ctx context.Context, c client.Reader, obj runtime.Object, interval time.Duration,
+	completed func(runtime.Object) bool, progress func(runtime.Object) interface{}, onUpdate func(runtime.Object)) (runtime.Object, error) {
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return obj, err
+	}
+	var last interface{}
+	err = wait.PollImmediateUntil(interval, func() (bool, error) {
+		current := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+		if err := c.Get(ctx, key, current); err != nil {
+			return false, err
+		}
+		if onUpdate != nil {
+			if p := progress(current); last == nil || !reflect.DeepEqual(last, p) {
+				onUpdate(current)
+				last = p
+			}
+		}
+		obj = current
+		return completed(obj), nil
+	}, ctx.Done())
+	return obj, err
+}
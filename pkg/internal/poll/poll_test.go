@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poll
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// phasingClient moves the pod through the phases, one phase per Get.
+type phasingClient struct {
+	client.Reader
+	phases []corev1.PodPhase
+	gets   int
+}
+
+func (c *phasingClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if err := c.Reader.Get(ctx, key, obj); err != nil {
+		return err
+	}
+	if c.gets < len(c.phases) {
+		obj.(*corev1.Pod).Status.Phase = c.phases[c.gets]
+	} else {
+		obj.(*corev1.Pod).Status.Phase = c.phases[len(c.phases)-1]
+	}
+	c.gets++
+	return nil
+}
+
+func TestUntilCompleted(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+	completed := func(obj runtime.Object) bool { return obj.(*corev1.Pod).Status.Phase == corev1.PodSucceeded }
+	progress := func(obj runtime.Object) interface{} { return obj.(*corev1.Pod).Status.Phase }
+
+	c := &phasingClient{
+		Reader: fake.NewFakeClient(pod.DeepCopy()),
+		phases: []corev1.PodPhase{corev1.PodPending, corev1.PodPending, corev1.PodRunning, corev1.PodSucceeded},
+	}
+	var updated []corev1.PodPhase
+	obj, err := UntilCompleted(context.TODO(), c, pod, time.Millisecond, completed, progress, func(obj runtime.Object) {
+		updated = append(updated, obj.(*corev1.Pod).Status.Phase)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.PodSucceeded, obj.(*corev1.Pod).Status.Phase)
+	assert.Equal(t, []corev1.PodPhase{corev1.PodPending, corev1.PodRunning, corev1.PodSucceeded}, updated)
+	assert.Equal(t, 4, c.gets)
+
+	c = &phasingClient{Reader: fake.NewFakeClient(pod.DeepCopy()), phases: []corev1.PodPhase{corev1.PodRunning}}
+	ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	obj, err = UntilCompleted(ctx, c, pod, time.Millisecond, completed, progress, nil)
+	assert.Equal(t, wait.ErrWaitTimeout, err)
+	assert.Equal(t, corev1.PodRunning, obj.(*corev1.Pod).Status.Phase)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RecordRevisionChangeCause writes the change-cause annotation of the Kruise workload onto the existing
+// ControllerRevision which records its current template.
+// Kruise controllers copy the annotations of the workload only when a new ControllerRevision is created, so
+// the change-cause would be lost when the template goes back to a previous revision.
+// It does nothing for other kinds, or if no such ControllerRevision exists yet.
+func RecordRevisionChangeCause(k kubernetes.Interface, obj runtime.Object) error {
+	var patch []byte
+	var selector *metav1.LabelSelector
+	var err error
+	switch obj := obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		patch, err = getCloneSetPatch(obj)
+		selector = obj.Spec.Selector
+	case *kruiseappsv1beta1.StatefulSet:
+		patch, err = getAdvancedStatefulSetPatch(obj)
+		selector = obj.Spec.Selector
+	case *kruiseappsv1alpha1.DaemonSet:
+		patch, err = getTemplatePatch(obj)
+		selector = obj.Spec.Selector
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		patch, err = getTemplatePatch(obj)
+		selector = obj.Spec.Selector
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	changeCause, ok := accessor.GetAnnotations()[ChangeCauseAnnotation]
+	if !ok {
+		return nil
+	}
+
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return err
+	}
+	history, err := controlledHistoryV1(k.AppsV1(), accessor.GetNamespace(), labelSelector, accessor)
+	if err != nil {
+		return err
+	}
+	for _, h := range history {
+		if bytes.Equal(patch, h.Data.Raw) {
+			return annotateHistory(k, h, map[string]string{ChangeCauseAnnotation: changeCause})
+		}
+	}
+	return nil
+}
+
+// rollbackAnnotations returns the annotations to write onto the workload when rolling back to history,
+// which restore the change-cause recorded in history unless overridden by updatedAnnotations.
+func rollbackAnnotations(history *appsv1.ControllerRevision, updatedAnnotations map[string]string) map[string]string {
+	annotations := map[string]string{}
+	if changeCause, ok := history.Annotations[ChangeCauseAnnotation]; ok {
+		annotations[ChangeCauseAnnotation] = changeCause
+	}
+	for k, v := range updatedAnnotations {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// recordRollbackChangeCause writes the change-cause of the rollback onto the restored ControllerRevision,
+// in case it is overridden by the updated annotations.
+func recordRollbackChangeCause(k kubernetes.Interface, history *appsv1.ControllerRevision, annotations map[string]string) error {
+	changeCause, ok := annotations[ChangeCauseAnnotation]
+	if !ok {
+		return nil
+	}
+	return annotateHistory(k, history, map[string]string{ChangeCauseAnnotation: changeCause})
+}
+
+// addAnnotationsToPatch returns the merge patch of a revision with the metadata annotations added.
+func addAnnotationsToPatch(patch []byte, annotations map[string]string) ([]byte, error) {
+	if len(annotations) == 0 {
+		return patch, nil
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(patch, &raw); err != nil {
+		return nil, err
+	}
+	raw["metadata"] = map[string]interface{}{"annotations": annotations}
+	return json.Marshal(raw)
+}
+
+// annotateHistory sets the annotations onto the ControllerRevision if they are changed.
+func annotateHistory(k kubernetes.Interface, history *appsv1.ControllerRevision, annotations map[string]string) error {
+	changed := false
+	for key, value := range annotations {
+		if v, ok := history.Annotations[key]; !ok || v != value {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	if _, err := k.AppsV1().ControllerRevisions(history.Namespace).Patch(context.TODO(), history.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to annotate ControllerRevision %s: %v", history.Name, err)
+	}
+	return nil
+}
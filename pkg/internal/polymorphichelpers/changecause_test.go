@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRollbackAnnotations(t *testing.T) {
+	history := &appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{ChangeCauseAnnotation: "kubectl-kruise set image cloneset/foo app=nginx:1.19"},
+	}}
+
+	annotations := rollbackAnnotations(history, nil)
+	if annotations[ChangeCauseAnnotation] != "kubectl-kruise set image cloneset/foo app=nginx:1.19" {
+		t.Errorf("expected the change-cause of the revision to be restored, got %v", annotations)
+	}
+
+	annotations = rollbackAnnotations(history, map[string]string{ChangeCauseAnnotation: "kubectl-kruise rollout undo cloneset/foo"})
+	if annotations[ChangeCauseAnnotation] != "kubectl-kruise rollout undo cloneset/foo" {
+		t.Errorf("expected the change-cause to be overridden, got %v", annotations)
+	}
+
+	if annotations := rollbackAnnotations(&appsv1.ControllerRevision{}, nil); len(annotations) != 0 {
+		t.Errorf("expected no annotations, got %v", annotations)
+	}
+}
+
+func TestAddAnnotationsToPatch(t *testing.T) {
+	patch := []byte(`{"spec":{"template":{"$patch":"replace"}}}`)
+
+	got, err := addAnnotationsToPatch(patch, nil)
+	if err != nil || string(got) != string(patch) {
+		t.Fatalf("expected the patch unchanged, got %s, err %v", got, err)
+	}
+
+	got, err = addAnnotationsToPatch(patch, map[string]string{ChangeCauseAnnotation: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"metadata":{"annotations":{"kubernetes.io/change-cause":"foo"}},"spec":{"template":{"$patch":"replace"}}}`
+	if string(got) != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
@@ -30,6 +30,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -37,10 +38,7 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	coreclient "k8s.io/client-go/kubernetes/typed/core/v1"
 	watchtools "k8s.io/client-go/tools/watch"
-)
-
-const (
-	RestartedEnv = "RESTARTED_AT"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // GetFirstPod returns a pod matching the namespace and label selector
@@ -183,6 +181,37 @@ func SelectorsForObject(object runtime.Object) (namespace string, selector label
 			return "", nil, fmt.Errorf("invalid label selector: %v", err)
 		}
 
+	case *kruiseappsv1alpha1.CloneSet:
+		namespace = t.Namespace
+		selector, err = metav1.LabelSelectorAsSelector(t.Spec.Selector)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid label selector: %v", err)
+		}
+	case *kruiseappsv1beta1.StatefulSet:
+		namespace = t.Namespace
+		selector, err = metav1.LabelSelectorAsSelector(t.Spec.Selector)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid label selector: %v", err)
+		}
+	case *kruiseappsv1alpha1.StatefulSet:
+		namespace = t.Namespace
+		selector, err = metav1.LabelSelectorAsSelector(t.Spec.Selector)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid label selector: %v", err)
+		}
+	case *kruiseappsv1alpha1.DaemonSet:
+		namespace = t.Namespace
+		selector, err = metav1.LabelSelectorAsSelector(t.Spec.Selector)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid label selector: %v", err)
+		}
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		namespace = t.Namespace
+		selector, err = metav1.LabelSelectorAsSelector(t.Spec.Selector)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid label selector: %v", err)
+		}
+
 	case *corev1.Service:
 		namespace = t.Namespace
 		if t.Spec.Selector == nil || len(t.Spec.Selector) == 0 {
@@ -197,60 +226,100 @@ func SelectorsForObject(object runtime.Object) (namespace string, selector label
 	return namespace, selector, nil
 }
 
-func findEnv(env []corev1.EnvVar, name string) (corev1.EnvVar, bool) {
-	for _, e := range env {
-		if e.Name == name {
-			return e, true
-		}
+// ControlledPods returns the pods controlled by the given workload, sorted by name.
+// The pods of a Deployment are controlled by its ReplicaSets, and those of a UnitedDeployment
+// by its subsets, or the ReplicaSets of its Deployment subsets, so they are resolved through these owners.
+func ControlledPods(c client.Reader, object runtime.Object) ([]*corev1.Pod, error) {
+	namespace, selector, err := SelectorsForObject(object)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := meta.Accessor(object)
+	if err != nil {
+		return nil, err
 	}
-	return corev1.EnvVar{}, false
-}
 
-func updateEnv(existing []corev1.EnvVar, env []corev1.EnvVar, remove []string) []corev1.EnvVar {
-	var out []corev1.EnvVar
-	covered := sets.NewString(remove...)
-	for _, e := range existing {
-		if covered.Has(e.Name) {
-			continue
+	owners := sets.NewString(string(owner.GetUID()))
+	switch object.(type) {
+	case *appsv1.Deployment, *appsv1beta1.Deployment, *appsv1beta2.Deployment, *extensionsv1beta1.Deployment:
+		if owners, err = controlledUIDs(c, namespace, owners, &appsv1.ReplicaSetList{}); err != nil {
+			return nil, err
+		}
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		subsets, err := controlledUIDs(c, namespace, owners,
+			&appsv1.StatefulSetList{}, &kruiseappsv1beta1.StatefulSetList{}, &kruiseappsv1alpha1.CloneSetList{}, &appsv1.DeploymentList{})
+		if err != nil {
+			return nil, err
 		}
-		newer, ok := findEnv(env, e.Name)
-		if ok {
-			covered.Insert(e.Name)
-			out = append(out, newer)
-			continue
+		replicaSets, err := controlledUIDs(c, namespace, subsets, &appsv1.ReplicaSetList{})
+		if err != nil {
+			return nil, err
 		}
-		out = append(out, e)
+		owners = subsets.Union(replicaSets)
+	}
+
+	podList := &corev1.PodList{}
+	if err := c.List(context.TODO(), podList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
 	}
-	for _, e := range env {
-		if covered.Has(e.Name) {
-			continue
+	var pods []*corev1.Pod
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if ref := metav1.GetControllerOf(pod); ref != nil && owners.Has(string(ref.UID)) {
+			pods = append(pods, pod)
 		}
-		covered.Insert(e.Name)
-		out = append(out, e)
 	}
-	return out
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods, nil
 }
 
-func UpdateResourceEnv(object runtime.Object) {
-	var addingEnvs []corev1.EnvVar
-	var restartEnv = corev1.EnvVar{
-		Name:  RestartedEnv,
-		Value: time.Now().Format(time.RFC3339),
+// controlledUIDs returns the UIDs of the objects of the lists in the namespace which are controlled by any of the owners.
+func controlledUIDs(c client.Reader, namespace string, owners sets.String, lists ...runtime.Object) (sets.String, error) {
+	uids := sets.NewString()
+	for _, list := range lists {
+		if err := c.List(context.TODO(), list, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			obj, err := meta.Accessor(item)
+			if err != nil {
+				return nil, err
+			}
+			if ref := metav1.GetControllerOf(obj); ref != nil && owners.Has(string(ref.UID)) {
+				uids.Insert(string(obj.GetUID()))
+			}
+		}
 	}
-	addingEnvs = append(addingEnvs, restartEnv)
+	return uids, nil
+}
 
-	switch obj := object.(type) {
-	case *kruiseappsv1alpha1.CloneSet:
-		for i, _ := range obj.Spec.Template.Spec.Containers {
-			tmp := &obj.Spec.Template.Spec.Containers[i]
-			tmp.Env = updateEnv(tmp.Env, addingEnvs, []string{})
+// SelectedDaemonNodesCounter returns a function counting the nodes which match the selector and run
+// pods of the Advanced DaemonSet, i.e. the pods to be updated by a rolling update with rollingUpdate.selector.
+func SelectedDaemonNodesCounter(c client.Reader) func(daemon *kruiseappsv1alpha1.DaemonSet, selector labels.Selector) (int32, error) {
+	return func(daemon *kruiseappsv1alpha1.DaemonSet, selector labels.Selector) (int32, error) {
+		nodeList := &corev1.NodeList{}
+		if err := c.List(context.TODO(), nodeList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return 0, err
+		}
+		nodes := sets.NewString()
+		for _, node := range nodeList.Items {
+			nodes.Insert(node.Name)
 		}
 
-	case *kruiseappsv1beta1.StatefulSet:
-		for i, _ := range obj.Spec.Template.Spec.Containers {
-			tmp := &obj.Spec.Template.Spec.Containers[i]
-			tmp.Env = updateEnv(tmp.Env, addingEnvs, []string{})
+		pods, err := ControlledPods(c, daemon)
+		if err != nil {
+			return 0, err
+		}
+		selected := sets.NewString()
+		for _, pod := range pods {
+			if nodes.Has(pod.Spec.NodeName) {
+				selected.Insert(pod.Spec.NodeName)
+			}
 		}
+		return int32(selected.Len()), nil
 	}
-
 }
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSelectedDaemonNodesCounter(t *testing.T) {
+	daemon := &kruiseappsv1alpha1.DaemonSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps.kruise.io/v1alpha1", Kind: "DaemonSet"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "agent", UID: "agent-uid"},
+		Spec:       kruiseappsv1alpha1.DaemonSetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "agent"}}},
+	}
+	isController := true
+	newPod := func(name, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "default",
+				Name:            name,
+				Labels:          map[string]string{"app": "agent"},
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps.kruise.io/v1alpha1", Kind: "DaemonSet", Name: "agent", UID: "agent-uid", Controller: &isController}},
+			},
+			Spec: corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+	newNode := func(name string, canary bool) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if canary {
+			node.Labels = map[string]string{"canary": "true"}
+		}
+		return node
+	}
+
+	// node-c is selected but runs no pod of the daemonset, e.g. for taints
+	c := fake.NewFakeClientWithScheme(api.GetScheme(), daemon,
+		newNode("node-a", true), newNode("node-b", false), newNode("node-c", true),
+		newPod("agent-a", "node-a"), newPod("agent-b", "node-b"))
+
+	selected, err := SelectedDaemonNodesCounter(c)(daemon, labels.SelectorFromSet(labels.Set{"canary": "true"}))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), selected)
+}
+
+func TestControlledPods(t *testing.T) {
+	isController := true
+	controllerRef := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, UID: types.UID(name + "-uid"), Controller: &isController}}
+	}
+	newPod := func(name, app string, owners []metav1.OwnerReference) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default", Name: name, Labels: map[string]string{"app": app}, OwnerReferences: owners,
+		}}
+	}
+	selector := func(app string) *metav1.LabelSelector {
+		return &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "web-uid"},
+		Spec:       appsv1.DeploymentSpec{Selector: selector("web")},
+	}
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "web-abc", UID: "web-abc-uid", OwnerReferences: controllerRef("Deployment", "web"),
+	}}
+	unitedDeployment := &kruiseappsv1alpha1.UnitedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db", UID: "db-uid"},
+		Spec:       kruiseappsv1alpha1.UnitedDeploymentSpec{Selector: selector("db")},
+	}
+	cloneSet := &kruiseappsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "db-zone-a", UID: "db-zone-a-uid", OwnerReferences: controllerRef("UnitedDeployment", "db"),
+	}}
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "db-zone-b", UID: "db-zone-b-uid", OwnerReferences: controllerRef("UnitedDeployment", "db"),
+	}}
+	subsetDeployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "db-zone-c", UID: "db-zone-c-uid", OwnerReferences: controllerRef("UnitedDeployment", "db"),
+	}}
+	subsetReplicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "db-zone-c-abc", UID: "db-zone-c-abc-uid", OwnerReferences: controllerRef("Deployment", "db-zone-c"),
+	}}
+
+	// the orphans match the selectors but are not controlled by the workloads
+	c := fake.NewFakeClientWithScheme(api.GetScheme(), deployment, replicaSet, unitedDeployment, cloneSet, statefulSet, subsetDeployment, subsetReplicaSet,
+		newPod("web-abc-2", "web", controllerRef("ReplicaSet", "web-abc")),
+		newPod("web-abc-1", "web", controllerRef("ReplicaSet", "web-abc")),
+		newPod("web-orphan", "web", nil),
+		newPod("db-zone-a-1", "db", controllerRef("CloneSet", "db-zone-a")),
+		newPod("db-zone-b-0", "db", controllerRef("StatefulSet", "db-zone-b")),
+		newPod("db-zone-c-abc-1", "db", controllerRef("ReplicaSet", "db-zone-c-abc")),
+		newPod("db-orphan", "db", controllerRef("CloneSet", "other")))
+
+	podNames := func(pods []*corev1.Pod) []string {
+		var names []string
+		for _, pod := range pods {
+			names = append(names, pod.Name)
+		}
+		return names
+	}
+	pods, err := ControlledPods(c, deployment)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web-abc-1", "web-abc-2"}, podNames(pods))
+
+	pods, err = ControlledPods(c, unitedDeployment)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db-zone-a-1", "db-zone-b-0", "db-zone-c-abc-1"}, podNames(pods))
+}
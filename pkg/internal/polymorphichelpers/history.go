@@ -22,6 +22,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"text/tabwriter"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
@@ -29,6 +31,7 @@ import (
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
 	"github.com/openkruise/kruise-tools/pkg/fetcher"
 	internalapps "github.com/openkruise/kruise-tools/pkg/internal/apps"
+	"github.com/pmezard/go-difflib/difflib"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -46,15 +49,29 @@ import (
 	deploymentutil "k8s.io/kubectl/pkg/util/deployment"
 	sliceutil "k8s.io/kubectl/pkg/util/slice"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 )
 
 const (
 	ChangeCauseAnnotation = "kubernetes.io/change-cause"
 )
 
+// RevisionHistory is a revision in the rollout history of a resource.
+type RevisionHistory struct {
+	Revision int64 `json:"revision"`
+	// Name is the name of the ControllerRevision, or the ReplicaSet for deployments.
+	Name              string                  `json:"name"`
+	CreationTimestamp metav1.Time             `json:"creationTimestamp"`
+	ChangeCause       string                  `json:"changeCause,omitempty"`
+	Images            []string                `json:"images,omitempty"`
+	Template          *corev1.PodTemplateSpec `json:"template,omitempty"`
+}
+
 // HistoryViewer provides an interface for resources have historical information.
 type HistoryViewer interface {
 	ViewHistory(namespace, name string, revision int64) (string, error)
+	// GetHistory returns each revision and the current pod template of the resource.
+	GetHistory(namespace, name string) (map[int64]*RevisionHistory, *corev1.PodTemplateSpec, error)
 }
 
 type HistoryVisitor struct {
@@ -116,6 +133,16 @@ type AdvancedStatefulSetHistoryViewer struct {
 	k kubernetes.Interface
 }
 
+type AdvancedDaemonSetHistoryViewer struct {
+	c client.Reader
+	k kubernetes.Interface
+}
+
+type UnitedDeploymentHistoryViewer struct {
+	c client.Reader
+	k kubernetes.Interface
+}
+
 func (v *HistoryVisitor) VisitCloneSet(kind internalapps.GroupKindElement) {
 	mgr := internalapi.NewManager()
 	v.c = mgr.GetAPIReader()
@@ -128,6 +155,18 @@ func (v *HistoryVisitor) VisitAdvancedStatefulSet(kind internalapps.GroupKindEle
 	v.result = &AdvancedStatefulSetHistoryViewer{v.c, v.clientset}
 }
 
+func (v *HistoryVisitor) VisitAdvancedDaemonSet(kind internalapps.GroupKindElement) {
+	mgr := internalapi.NewManager()
+	v.c = mgr.GetAPIReader()
+	v.result = &AdvancedDaemonSetHistoryViewer{v.c, v.clientset}
+}
+
+func (v *HistoryVisitor) VisitUnitedDeployment(kind internalapps.GroupKindElement) {
+	mgr := internalapi.NewManager()
+	v.c = mgr.GetAPIReader()
+	v.result = &UnitedDeploymentHistoryViewer{v.c, v.clientset}
+}
+
 // TODO impl ViewHistory func for CloneSet
 func (h *CloneSetHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
 
@@ -145,6 +184,24 @@ func (h *CloneSetHistoryViewer) ViewHistory(namespace, name string, revision int
 	})
 }
 
+func (h *CloneSetHistoryViewer) GetHistory(namespace, name string) (map[int64]*RevisionHistory, *corev1.PodTemplateSpec, error) {
+	cs, history, err := clonesetHistory(h.k.AppsV1(), h.c, namespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions, err := revisionHistories(history, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+		csOfHistory, err := applyCloneSetHistory(cs, history)
+		if err != nil {
+			return nil, err
+		}
+		return &csOfHistory.Spec.Template, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return revisions, &cs.Spec.Template, nil
+}
+
 func (h *AdvancedStatefulSetHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
 	asts, history, err := advancedstsHistory(h.k.AppsV1(), h.c, namespace, name)
 	if err != nil {
@@ -159,6 +216,92 @@ func (h *AdvancedStatefulSetHistoryViewer) ViewHistory(namespace, name string, r
 	})
 }
 
+func (h *AdvancedStatefulSetHistoryViewer) GetHistory(namespace, name string) (map[int64]*RevisionHistory, *corev1.PodTemplateSpec, error) {
+	asts, history, err := advancedstsHistory(h.k.AppsV1(), h.c, namespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions, err := revisionHistories(history, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+		astsOfHistory, err := applyAdvancedStatefulSetHistory(asts, history)
+		if err != nil {
+			return nil, err
+		}
+		return &astsOfHistory.Spec.Template, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return revisions, &asts.Spec.Template, nil
+}
+
+func (h *AdvancedDaemonSetHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
+	ds, history, err := advancedDaemonSetHistory(h.k.AppsV1(), h.c, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return printHistory(history, revision, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+		dsOfHistory, err := applyAdvancedDaemonSetHistory(ds, history)
+		if err != nil {
+			return nil, err
+		}
+		return &dsOfHistory.Spec.Template, err
+	})
+}
+
+func (h *AdvancedDaemonSetHistoryViewer) GetHistory(namespace, name string) (map[int64]*RevisionHistory, *corev1.PodTemplateSpec, error) {
+	ds, history, err := advancedDaemonSetHistory(h.k.AppsV1(), h.c, namespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions, err := revisionHistories(history, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+		dsOfHistory, err := applyAdvancedDaemonSetHistory(ds, history)
+		if err != nil {
+			return nil, err
+		}
+		return &dsOfHistory.Spec.Template, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return revisions, &ds.Spec.Template, nil
+}
+
+func (h *UnitedDeploymentHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
+	ud, history, err := unitedDeploymentHistory(h.k.AppsV1(), h.c, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return printHistory(history, revision, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+		udOfHistory, err := applyUnitedDeploymentHistory(ud, history)
+		if err != nil {
+			return nil, err
+		}
+		return UnitedDeploymentPodTemplate(udOfHistory)
+	})
+}
+
+func (h *UnitedDeploymentHistoryViewer) GetHistory(namespace, name string) (map[int64]*RevisionHistory, *corev1.PodTemplateSpec, error) {
+	ud, history, err := unitedDeploymentHistory(h.k.AppsV1(), h.c, namespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions, err := revisionHistories(history, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+		udOfHistory, err := applyUnitedDeploymentHistory(ud, history)
+		if err != nil {
+			return nil, err
+		}
+		return UnitedDeploymentPodTemplate(udOfHistory)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := UnitedDeploymentPodTemplate(ud)
+	if err != nil {
+		return nil, nil, err
+	}
+	return revisions, current, nil
+}
+
 // ViewHistory returns a revision-to-replicaset map as the revision history of a deployment
 // TODO: this should be a describer
 func (h *DeploymentHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
@@ -226,6 +369,35 @@ func (h *DeploymentHistoryViewer) ViewHistory(namespace, name string, revision i
 	})
 }
 
+// GetHistory returns the revision history of each ReplicaSet of the deployment, whose pod template is without the pod-template-hash label.
+func (h *DeploymentHistoryViewer) GetHistory(namespace, name string) (map[int64]*RevisionHistory, *corev1.PodTemplateSpec, error) {
+	versionedAppsClient := h.c.AppsV1()
+	deployment, err := versionedAppsClient.Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve deployment %s: %v", name, err)
+	}
+	_, allOldRSs, newRS, err := deploymentutil.GetAllReplicaSets(deployment, versionedAppsClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve replica sets from deployment %s: %v", name, err)
+	}
+	allRSs := allOldRSs
+	if newRS != nil {
+		allRSs = append(allRSs, newRS)
+	}
+
+	revisions := make(map[int64]*RevisionHistory)
+	for _, rs := range allRSs {
+		v, err := deploymentutil.Revision(rs)
+		if err != nil {
+			continue
+		}
+		template := rs.Spec.Template.DeepCopy()
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		revisions[v] = newRevisionHistory(v, &rs.ObjectMeta, template)
+	}
+	return revisions, &deployment.Spec.Template, nil
+}
+
 func printTemplate(template *corev1.PodTemplateSpec) (string, error) {
 	buf := bytes.NewBuffer([]byte{})
 	w := describe.NewPrefixWriter(buf)
@@ -253,6 +425,165 @@ func (h *DaemonSetHistoryViewer) ViewHistory(namespace, name string, revision in
 	})
 }
 
+func (h *DaemonSetHistoryViewer) GetHistory(namespace, name string) (map[int64]*RevisionHistory, *corev1.PodTemplateSpec, error) {
+	ds, history, err := daemonSetHistory(h.c.AppsV1(), namespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions, err := revisionHistories(history, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+		dsOfHistory, err := applyDaemonSetHistory(ds, history)
+		if err != nil {
+			return nil, err
+		}
+		return &dsOfHistory.Spec.Template, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return revisions, &ds.Spec.Template, nil
+}
+
+// revisionHistories returns the revision history of each ControllerRevision in history.
+func revisionHistories(history []*appsv1.ControllerRevision,
+	getPodTemplate func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error)) (map[int64]*RevisionHistory, error) {
+	revisions := make(map[int64]*RevisionHistory, len(history))
+	for _, h := range history {
+		template, err := getPodTemplate(h)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse history %s: %v", h.Name, err)
+		}
+		revisions[h.Revision] = newRevisionHistory(h.Revision, &h.ObjectMeta, template)
+	}
+	return revisions, nil
+}
+
+func newRevisionHistory(revision int64, meta *metav1.ObjectMeta, template *corev1.PodTemplateSpec) *RevisionHistory {
+	var images []string
+	for _, c := range template.Spec.InitContainers {
+		images = append(images, c.Image)
+	}
+	for _, c := range template.Spec.Containers {
+		images = append(images, c.Image)
+	}
+	return &RevisionHistory{
+		Revision:          revision,
+		Name:              meta.Name,
+		CreationTimestamp: meta.CreationTimestamp,
+		ChangeCause:       meta.Annotations[ChangeCauseAnnotation],
+		Images:            images,
+		Template:          template,
+	}
+}
+
+// DiffHistory returns a unified diff between the pod templates of revision and toRevision of the resource.
+// If toRevision is 0, the current pod template is compared instead.
+func DiffHistory(viewer HistoryViewer, namespace, name string, revision, toRevision int64) (string, error) {
+	revisions, current, err := viewer.GetHistory(namespace, name)
+	if err != nil {
+		return "", err
+	}
+	from, ok := revisions[revision]
+	if !ok {
+		return "", fmt.Errorf("unable to find the specified revision %d", revision)
+	}
+	to, toName := current, "current"
+	if toRevision > 0 {
+		toHistory, ok := revisions[toRevision]
+		if !ok {
+			return "", fmt.Errorf("unable to find the specified revision %d", toRevision)
+		}
+		to, toName = toHistory.Template, fmt.Sprintf("revision %d", toRevision)
+	}
+	return DiffPodTemplates(fmt.Sprintf("revision %d", revision), toName, from.Template, to)
+}
+
+// RevisionSelector selects a revision either by the name of its ControllerRevision,
+// or by the images of its containers keyed by container name.
+type RevisionSelector struct {
+	Name   string
+	Images map[string]string
+}
+
+// FindRevision returns the revision of the resource selected by selector.
+// If several revisions have the selected images, the latest one is returned.
+func FindRevision(viewer HistoryViewer, namespace, name string, selector RevisionSelector) (int64, error) {
+	revisions, _, err := viewer.GetHistory(namespace, name)
+	if err != nil {
+		return 0, err
+	}
+	var found int64
+	for revision, history := range revisions {
+		if revision > found && revisionMatches(history, selector) {
+			found = revision
+		}
+	}
+	if found == 0 {
+		if len(selector.Name) > 0 {
+			return 0, fmt.Errorf("unable to find the revision named %s", selector.Name)
+		}
+		return 0, fmt.Errorf("unable to find a revision with images %s", formatImages(selector.Images))
+	}
+	return found, nil
+}
+
+func revisionMatches(history *RevisionHistory, selector RevisionSelector) bool {
+	if len(selector.Name) > 0 && history.Name != selector.Name {
+		return false
+	}
+	for container, image := range selector.Images {
+		if !templateHasImage(history.Template, container, image) {
+			return false
+		}
+	}
+	return true
+}
+
+func templateHasImage(template *corev1.PodTemplateSpec, container, image string) bool {
+	for _, containers := range [][]corev1.Container{template.Spec.InitContainers, template.Spec.Containers} {
+		for _, c := range containers {
+			if c.Name == container {
+				return c.Image == image
+			}
+		}
+	}
+	return false
+}
+
+func formatImages(images map[string]string) string {
+	var pairs []string
+	for container, image := range images {
+		pairs = append(pairs, container+"="+image)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// DiffPodTemplates returns a unified diff between the YAML of the two pod templates.
+func DiffPodTemplates(fromName, toName string, from, to *corev1.PodTemplateSpec) (string, error) {
+	fromYAML, err := yaml.Marshal(from)
+	if err != nil {
+		return "", err
+	}
+	toYAML, err := yaml.Marshal(to)
+	if err != nil {
+		return "", err
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(fromYAML)),
+		B:        difflib.SplitLines(string(toYAML)),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(diff) == 0 {
+		return fmt.Sprintf("No differences between %s and %s.\n", fromName, toName), nil
+	}
+	return diff, nil
+}
+
 // printHistory returns the podTemplate of the given revision if it is non-zero
 // else returns the overall revisions
 func printHistory(history []*appsv1.ControllerRevision, revision int64, getPodTemplate func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error)) (string, error) {
@@ -320,6 +651,24 @@ func (h *StatefulSetHistoryViewer) ViewHistory(namespace, name string, revision
 	})
 }
 
+func (h *StatefulSetHistoryViewer) GetHistory(namespace, name string) (map[int64]*RevisionHistory, *corev1.PodTemplateSpec, error) {
+	sts, history, err := statefulSetHistory(h.c.AppsV1(), namespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions, err := revisionHistories(history, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+		stsOfHistory, err := applyStatefulSetHistory(sts, history)
+		if err != nil {
+			return nil, err
+		}
+		return &stsOfHistory.Spec.Template, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return revisions, &sts.Spec.Template, nil
+}
+
 // controlledHistories returns all ControllerRevisions in namespace that selected by selector and owned by accessor
 // TODO: Rename this to controllerHistory when other controllers have been upgraded
 func controlledHistoryV1(
@@ -434,6 +783,50 @@ func advancedstsHistory(
 	return asts, history, nil
 }
 
+// advancedDaemonSetHistory returns the Advanced DaemonSet named name in namespace and all ControllerRevisions in its history.
+func advancedDaemonSetHistory(
+	apps clientappsv1.AppsV1Interface, cr client.Reader,
+	namespace, name string) (*kruiseappsv1alpha1.DaemonSet, []*appsv1.ControllerRevision, error) {
+	ds, found, err := fetcher.GetAdvancedDaemonSetInCache(namespace, name, cr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve Advanced DaemonSet %s: %v", name, err)
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("failed to retrieve Advanced DaemonSet %s: not found", name)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create selector for Advanced DaemonSet %s: %v", name, err)
+	}
+	history, err := controlledHistoryV1(apps, namespace, selector, ds)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to find history controlled by Advanced DaemonSet %s: %v", name, err)
+	}
+	return ds, history, nil
+}
+
+// unitedDeploymentHistory returns the UnitedDeployment named name in namespace and all ControllerRevisions in its history.
+func unitedDeploymentHistory(
+	apps clientappsv1.AppsV1Interface, cr client.Reader,
+	namespace, name string) (*kruiseappsv1alpha1.UnitedDeployment, []*appsv1.ControllerRevision, error) {
+	ud, found, err := fetcher.GetUnitedDeploymentInCache(namespace, name, cr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve UnitedDeployment %s: %v", name, err)
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("failed to retrieve UnitedDeployment %s: not found", name)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ud.Spec.Selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create selector for UnitedDeployment %s: %v", name, err)
+	}
+	history, err := controlledHistoryV1(apps, namespace, selector, ud)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to find history controlled by UnitedDeployment %s: %v", name, err)
+	}
+	return ud, history, nil
+}
+
 // statefulSetHistory returns the StatefulSet named name in namespace and all ControllerRevisions in its history.
 func statefulSetHistory(
 	apps clientappsv1.AppsV1Interface,
@@ -531,6 +924,60 @@ func applyAdvancedStatefulSetHistory(asts *kruiseappsv1beta1.StatefulSet,
 	return result, nil
 }
 
+// applyAdvancedDaemonSetHistory returns a specific revision of Advanced DaemonSet by applying the given history to a copy of the given Advanced DaemonSet
+func applyAdvancedDaemonSetHistory(ds *kruiseappsv1alpha1.DaemonSet,
+	history *appsv1.ControllerRevision) (*kruiseappsv1alpha1.DaemonSet, error) {
+	dsBytes, err := json.Marshal(ds)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(dsBytes, history.Data.Raw, ds)
+	if err != nil {
+		return nil, err
+	}
+	result := &kruiseappsv1alpha1.DaemonSet{}
+	err = json.Unmarshal(patched, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// applyUnitedDeploymentHistory returns a specific revision of UnitedDeployment by applying the given history to a copy of the given UnitedDeployment
+func applyUnitedDeploymentHistory(ud *kruiseappsv1alpha1.UnitedDeployment,
+	history *appsv1.ControllerRevision) (*kruiseappsv1alpha1.UnitedDeployment, error) {
+	udBytes, err := json.Marshal(ud)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(udBytes, history.Data.Raw, ud)
+	if err != nil {
+		return nil, err
+	}
+	result := &kruiseappsv1alpha1.UnitedDeployment{}
+	err = json.Unmarshal(patched, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UnitedDeploymentPodTemplate returns the pod template of the subset template of the given UnitedDeployment.
+func UnitedDeploymentPodTemplate(ud *kruiseappsv1alpha1.UnitedDeployment) (*corev1.PodTemplateSpec, error) {
+	template := &ud.Spec.Template
+	switch {
+	case template.StatefulSetTemplate != nil:
+		return &template.StatefulSetTemplate.Spec.Template, nil
+	case template.AdvancedStatefulSetTemplate != nil:
+		return &template.AdvancedStatefulSetTemplate.Spec.Template, nil
+	case template.CloneSetTemplate != nil:
+		return &template.CloneSetTemplate.Spec.Template, nil
+	case template.DeploymentTemplate != nil:
+		return &template.DeploymentTemplate.Spec.Template, nil
+	}
+	return nil, fmt.Errorf("UnitedDeployment %s has no subset template", ud.Name)
+}
+
 // TODO: copied here until this becomes a describer
 func tabbedString(f func(io.Writer) error) (string, error) {
 	out := new(tabwriter.Writer)
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+type fakeHistoryViewer struct {
+	revisions map[int64]*RevisionHistory
+	current   *corev1.PodTemplateSpec
+}
+
+func (v *fakeHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
+	return "", nil
+}
+
+func (v *fakeHistoryViewer) GetHistory(namespace, name string) (map[int64]*RevisionHistory, *corev1.PodTemplateSpec, error) {
+	return v.revisions, v.current, nil
+}
+
+func newTemplate(image string) *corev1.PodTemplateSpec {
+	return &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}}}
+}
+
+func TestDiffHistory(t *testing.T) {
+	viewer := &fakeHistoryViewer{
+		revisions: map[int64]*RevisionHistory{
+			1: {Revision: 1, Template: newTemplate("nginx:1.19")},
+			2: {Revision: 2, Template: newTemplate("nginx:1.20")},
+		},
+		current: newTemplate("nginx:1.20"),
+	}
+
+	diff, err := DiffHistory(viewer, "default", "foo", 1, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"--- revision 1", "+++ revision 2", "-  - image: nginx:1.19", "+  - image: nginx:1.20"} {
+		if !strings.Contains(diff, expected) {
+			t.Errorf("expected diff to contain %q, got:\n%s", expected, diff)
+		}
+	}
+
+	diff, err = DiffHistory(viewer, "default", "foo", 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff != "No differences between revision 2 and current.\n" {
+		t.Errorf("unexpected diff: %s", diff)
+	}
+
+	if _, err = DiffHistory(viewer, "default", "foo", 3, 0); err == nil {
+		t.Errorf("expected error for revision not found")
+	}
+}
+
+func TestFindRevision(t *testing.T) {
+	viewer := &fakeHistoryViewer{
+		revisions: map[int64]*RevisionHistory{
+			1: {Revision: 1, Name: "foo-5c8f7d", Template: newTemplate("nginx:1.19")},
+			2: {Revision: 2, Name: "foo-7b9c4f", Template: newTemplate("nginx:1.20")},
+			3: {Revision: 3, Name: "foo-6d4b8a", Template: newTemplate("nginx:1.19")},
+		},
+		current: newTemplate("nginx:1.19"),
+	}
+
+	tests := []struct {
+		name     string
+		selector RevisionSelector
+		expected int64
+		wantErr  bool
+	}{
+		{name: "by name", selector: RevisionSelector{Name: "foo-7b9c4f"}, expected: 2},
+		{name: "latest by image", selector: RevisionSelector{Images: map[string]string{"app": "nginx:1.19"}}, expected: 3},
+		{name: "name not found", selector: RevisionSelector{Name: "foo-unknown"}, wantErr: true},
+		{name: "image not found", selector: RevisionSelector{Images: map[string]string{"app": "nginx:1.21"}}, wantErr: true},
+		{name: "container not found", selector: RevisionSelector{Images: map[string]string{"sidecar": "nginx:1.19"}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			revision, err := FindRevision(viewer, "default", "foo", test.selector)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+			if revision != test.expected {
+				t.Errorf("expected revision %d, got %d", test.expected, revision)
+			}
+		})
+	}
+}
+
+func TestPrintPodTemplateDiff(t *testing.T) {
+	diff, err := printPodTemplateDiff(newTemplate("nginx:1.20"), newTemplate("nginx:1.19"), 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"will roll back to revision 3:\n", "--- current", "+++ revision 3", "-  - image: nginx:1.20", "+  - image: nginx:1.19"} {
+		if !strings.Contains(diff, expected) {
+			t.Errorf("expected diff to contain %q, got:\n%s", expected, diff)
+		}
+	}
+}
@@ -112,3 +112,11 @@ type ObjectRestarterFunc func(runtime.Object) ([]byte, error)
 // ObjectRestarterFn gives a way to easily override the function for unit testing if needed.
 // Returns the patched object in bytes and any error that occurred during the encoding.
 var ObjectRestarterFn ObjectRestarterFunc = defaultObjectRestarter
+
+// ObjectApproverFunc is a function type that lowers the partition of the object in a given info by step.
+type ObjectApproverFunc func(runtime.Object, ApproveStep) ([]byte, error)
+
+// ObjectApproverFn gives a way to easily override the function for unit testing if needed.
+// Returns the patched object in bytes and any error that occurred during the encoding or
+// in case the object has no partition left to approve.
+var ObjectApproverFn ObjectApproverFunc = defaultObjectApprover
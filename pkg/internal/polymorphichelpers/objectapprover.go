@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"errors"
+	"fmt"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// ApproveStep describes how far the partition is lowered by one approval.
+// Exactly one of Batch, To and All should be set.
+type ApproveStep struct {
+	// Batch is the number or percentage of replicas to lower the partition by.
+	Batch *intstr.IntOrString
+	// To is the partition to lower to.
+	To *int32
+	// All lowers the partition to 0, which releases all the remaining replicas.
+	All bool
+}
+
+// Currently supports CloneSets and Advanced StatefulSets.
+func defaultObjectApprover(obj runtime.Object, step ApproveStep) ([]byte, error) {
+	switch obj := obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		replicas := getReplicas(obj.Spec.Replicas)
+		current, err := CloneSetPartition(obj)
+		if err != nil {
+			return nil, err
+		}
+		next, err := nextPartition(current, replicas, step)
+		if err != nil {
+			return nil, err
+		}
+		partition := intstr.FromInt(int(next))
+		obj.Spec.UpdateStrategy.Partition = &partition
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1beta1.StatefulSet:
+		rollingUpdate := obj.Spec.UpdateStrategy.RollingUpdate
+		if rollingUpdate == nil || rollingUpdate.Partition == nil {
+			return nil, errors.New("has no partition to approve")
+		}
+		next, err := nextPartition(*rollingUpdate.Partition, getReplicas(obj.Spec.Replicas), step)
+		if err != nil {
+			return nil, err
+		}
+		rollingUpdate.Partition = &next
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1beta1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.StatefulSet:
+		rollingUpdate := obj.Spec.UpdateStrategy.RollingUpdate
+		if rollingUpdate == nil || rollingUpdate.Partition == nil {
+			return nil, errors.New("has no partition to approve")
+		}
+		next, err := nextPartition(*rollingUpdate.Partition, getReplicas(obj.Spec.Replicas), step)
+		if err != nil {
+			return nil, err
+		}
+		rollingUpdate.Partition = &next
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	default:
+		return nil, fmt.Errorf("approving is not supported")
+	}
+}
+
+// CloneSetPartition returns the partition of the CloneSet, with percentage resolved against its replicas.
+func CloneSetPartition(cs *kruiseappsv1alpha1.CloneSet) (int32, error) {
+	if cs.Spec.UpdateStrategy.Partition == nil {
+		return 0, nil
+	}
+	replicas := getReplicas(cs.Spec.Replicas)
+	// same as the CloneSet controller, percentage is rounded up
+	partition, err := intstr.GetValueFromIntOrPercent(cs.Spec.UpdateStrategy.Partition, int(replicas), true)
+	if err != nil {
+		return 0, fmt.Errorf("invalid partition %s: %v", cs.Spec.UpdateStrategy.Partition.String(), err)
+	}
+	if partition > int(replicas) {
+		partition = int(replicas)
+	}
+	return int32(partition), nil
+}
+
+// nextPartition returns the partition lowered from current by step.
+func nextPartition(current, replicas int32, step ApproveStep) (int32, error) {
+	if current <= 0 {
+		return 0, errors.New("has no partition to approve")
+	}
+
+	var next int32
+	switch {
+	case step.All:
+		next = 0
+	case step.To != nil:
+		next = *step.To
+		if next >= current {
+			return 0, fmt.Errorf("partition %d is already lower than or equal to %d", current, next)
+		}
+	case step.Batch != nil:
+		batch, err := intstr.GetValueFromIntOrPercent(step.Batch, int(replicas), true)
+		if err != nil {
+			return 0, fmt.Errorf("invalid batch %s: %v", step.Batch.String(), err)
+		}
+		if batch <= 0 {
+			return 0, fmt.Errorf("batch %s is less than 1 replica", step.Batch.String())
+		}
+		next = current - int32(batch)
+	default:
+		return 0, errors.New("no approve step specified")
+	}
+
+	if next < 0 {
+		next = 0
+	}
+	return next, nil
+}
+
+func getReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
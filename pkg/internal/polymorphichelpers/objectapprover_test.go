@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestDefaultObjectApproverCloneSet(t *testing.T) {
+	batch := intstr.FromString("20%")
+	tests := []struct {
+		name      string
+		partition intstr.IntOrString
+		step      ApproveStep
+		expected  int32
+		wantErr   bool
+	}{
+		{name: "batch percentage", partition: intstr.FromInt(8), step: ApproveStep{Batch: &batch}, expected: 6},
+		{name: "batch from percentage partition", partition: intstr.FromString("50%"), step: ApproveStep{Batch: &batch}, expected: 3},
+		{name: "to", partition: intstr.FromInt(8), step: ApproveStep{To: int32Ptr(5)}, expected: 5},
+		{name: "to higher partition", partition: intstr.FromInt(4), step: ApproveStep{To: int32Ptr(5)}, wantErr: true},
+		{name: "all", partition: intstr.FromInt(8), step: ApproveStep{All: true}, expected: 0},
+		{name: "nothing left", partition: intstr.FromInt(0), step: ApproveStep{All: true}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			partition := test.partition
+			cs := &kruiseappsv1alpha1.CloneSet{Spec: kruiseappsv1alpha1.CloneSetSpec{
+				Replicas:       int32Ptr(10),
+				UpdateStrategy: kruiseappsv1alpha1.CloneSetUpdateStrategy{Partition: &partition},
+			}}
+			data, err := defaultObjectApprover(cs, test.step)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+			if test.wantErr {
+				return
+			}
+
+			approved := &kruiseappsv1alpha1.CloneSet{}
+			if err := runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), data, approved); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := CloneSetPartition(approved); got != test.expected {
+				t.Errorf("expected partition %d, got %d", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestDefaultObjectApproverAdvancedStatefulSet(t *testing.T) {
+	batch := intstr.FromInt(3)
+	asts := &kruiseappsv1beta1.StatefulSet{Spec: kruiseappsv1beta1.StatefulSetSpec{
+		Replicas: int32Ptr(5),
+		UpdateStrategy: kruiseappsv1beta1.StatefulSetUpdateStrategy{
+			RollingUpdate: &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{Partition: int32Ptr(2)},
+		},
+	}}
+	if _, err := defaultObjectApprover(asts, ApproveStep{Batch: &batch}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *asts.Spec.UpdateStrategy.RollingUpdate.Partition != 0 {
+		t.Errorf("expected partition 0, got %d", *asts.Spec.UpdateStrategy.RollingUpdate.Partition)
+	}
+}
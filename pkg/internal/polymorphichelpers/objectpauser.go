@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 
 	appsv1 "k8s.io/api/apps/v1"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
@@ -30,7 +31,7 @@ import (
 	"k8s.io/kubectl/pkg/scheme"
 )
 
-// Currently only supports Deployments.
+// Currently supports Deployments, CloneSets, Advanced StatefulSets, Advanced DaemonSets, SidecarSets and UnitedDeployments.
 func defaultObjectPauser(obj runtime.Object) ([]byte, error) {
 	switch obj := obj.(type) {
 	case *extensionsv1beta1.Deployment:
@@ -66,8 +67,88 @@ func defaultObjectPauser(obj runtime.Object) ([]byte, error) {
 		}
 		obj.Spec.UpdateStrategy.Paused = true
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+	case *kruiseappsv1beta1.StatefulSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate != nil && obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return nil, errors.New("is already paused")
+		}
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil {
+			obj.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{}
+		}
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = true
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1beta1.SchemeGroupVersion), obj)
+	case *kruiseappsv1alpha1.StatefulSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate != nil && obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return nil, errors.New("is already paused")
+		}
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil {
+			obj.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateStatefulSetStrategy{}
+		}
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = true
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+	case *kruiseappsv1alpha1.DaemonSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate != nil && obj.Spec.UpdateStrategy.RollingUpdate.Paused != nil && *obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return nil, errors.New("is already paused")
+		}
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil {
+			obj.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateDaemonSet{}
+		}
+		paused := true
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = &paused
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+	case *kruiseappsv1alpha1.SidecarSet:
+		if obj.Spec.UpdateStrategy.Paused {
+			return nil, errors.New("is already paused")
+		}
+		obj.Spec.UpdateStrategy.Paused = true
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		changed, err := setUnitedDeploymentSubsetPaused(obj, true)
+		if err != nil {
+			return nil, err
+		} else if !changed {
+			return nil, errors.New("is already paused")
+		}
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
 
 	default:
 		return nil, fmt.Errorf("pausing is not supported")
 	}
 }
+
+// setUnitedDeploymentSubsetPaused pauses or resumes the workload template of UnitedDeployment subsets,
+// and returns whether the template is changed.
+func setUnitedDeploymentSubsetPaused(ud *kruiseappsv1alpha1.UnitedDeployment, paused bool) (bool, error) {
+	action := "resuming"
+	if paused {
+		action = "pausing"
+	}
+	template := &ud.Spec.Template
+	switch {
+	case template.StatefulSetTemplate != nil:
+		// native StatefulSets have no way to pause their rolling update
+		return false, fmt.Errorf("%s is not supported for UnitedDeployment with StatefulSet subsets", action)
+	case template.CloneSetTemplate != nil:
+		strategy := &template.CloneSetTemplate.Spec.UpdateStrategy
+		if strategy.Paused == paused {
+			return false, nil
+		}
+		strategy.Paused = paused
+	case template.AdvancedStatefulSetTemplate != nil:
+		strategy := &template.AdvancedStatefulSetTemplate.Spec.UpdateStrategy
+		if strategy.RollingUpdate == nil {
+			strategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateStatefulSetStrategy{}
+		}
+		if strategy.RollingUpdate.Paused == paused {
+			return false, nil
+		}
+		strategy.RollingUpdate.Paused = paused
+	case template.DeploymentTemplate != nil:
+		if template.DeploymentTemplate.Spec.Paused == paused {
+			return false, nil
+		}
+		template.DeploymentTemplate.Spec.Paused = paused
+	default:
+		return false, fmt.Errorf("%s is only supported for UnitedDeployment with CloneSet, Advanced StatefulSet or Deployment subsets", action)
+	}
+	return true, nil
+}
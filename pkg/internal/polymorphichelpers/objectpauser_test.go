@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestDefaultObjectPauserAndResumer(t *testing.T) {
+	paused := true
+	tests := []struct {
+		name string
+		// obj returns the object in its resumed state
+		obj    func() runtime.Object
+		pause  func(obj runtime.Object)
+		paused func(obj runtime.Object) bool
+		// unsupported objects can be neither paused nor resumed
+		unsupported bool
+	}{
+		{
+			name:   "cloneset",
+			obj:    func() runtime.Object { return &kruiseappsv1alpha1.CloneSet{} },
+			pause:  func(obj runtime.Object) { obj.(*kruiseappsv1alpha1.CloneSet).Spec.UpdateStrategy.Paused = true },
+			paused: func(obj runtime.Object) bool { return obj.(*kruiseappsv1alpha1.CloneSet).Spec.UpdateStrategy.Paused },
+		},
+		{
+			name: "advanced statefulset v1beta1",
+			obj:  func() runtime.Object { return &kruiseappsv1beta1.StatefulSet{} },
+			pause: func(obj runtime.Object) {
+				obj.(*kruiseappsv1beta1.StatefulSet).Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{Paused: true}
+			},
+			paused: func(obj runtime.Object) bool {
+				ru := obj.(*kruiseappsv1beta1.StatefulSet).Spec.UpdateStrategy.RollingUpdate
+				return ru != nil && ru.Paused
+			},
+		},
+		{
+			name: "advanced statefulset v1alpha1",
+			obj:  func() runtime.Object { return &kruiseappsv1alpha1.StatefulSet{} },
+			pause: func(obj runtime.Object) {
+				obj.(*kruiseappsv1alpha1.StatefulSet).Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateStatefulSetStrategy{Paused: true}
+			},
+			paused: func(obj runtime.Object) bool {
+				ru := obj.(*kruiseappsv1alpha1.StatefulSet).Spec.UpdateStrategy.RollingUpdate
+				return ru != nil && ru.Paused
+			},
+		},
+		{
+			name: "advanced daemonset",
+			obj:  func() runtime.Object { return &kruiseappsv1alpha1.DaemonSet{} },
+			pause: func(obj runtime.Object) {
+				obj.(*kruiseappsv1alpha1.DaemonSet).Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateDaemonSet{Paused: &paused}
+			},
+			paused: func(obj runtime.Object) bool {
+				ru := obj.(*kruiseappsv1alpha1.DaemonSet).Spec.UpdateStrategy.RollingUpdate
+				return ru != nil && ru.Paused != nil && *ru.Paused
+			},
+		},
+		{
+			name:   "sidecarset",
+			obj:    func() runtime.Object { return &kruiseappsv1alpha1.SidecarSet{} },
+			pause:  func(obj runtime.Object) { obj.(*kruiseappsv1alpha1.SidecarSet).Spec.UpdateStrategy.Paused = true },
+			paused: func(obj runtime.Object) bool { return obj.(*kruiseappsv1alpha1.SidecarSet).Spec.UpdateStrategy.Paused },
+		},
+		{
+			name: "uniteddeployment with cloneset subsets",
+			obj: func() runtime.Object {
+				ud := &kruiseappsv1alpha1.UnitedDeployment{}
+				ud.Spec.Template.CloneSetTemplate = &kruiseappsv1alpha1.CloneSetTemplateSpec{}
+				return ud
+			},
+			pause: func(obj runtime.Object) {
+				obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.CloneSetTemplate.Spec.UpdateStrategy.Paused = true
+			},
+			paused: func(obj runtime.Object) bool {
+				return obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.CloneSetTemplate.Spec.UpdateStrategy.Paused
+			},
+		},
+		{
+			name: "uniteddeployment with advanced statefulset subsets",
+			obj: func() runtime.Object {
+				ud := &kruiseappsv1alpha1.UnitedDeployment{}
+				ud.Spec.Template.AdvancedStatefulSetTemplate = &kruiseappsv1alpha1.AdvancedStatefulSetTemplateSpec{}
+				return ud
+			},
+			pause: func(obj runtime.Object) {
+				obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.AdvancedStatefulSetTemplate.Spec.UpdateStrategy.RollingUpdate =
+					&kruiseappsv1alpha1.RollingUpdateStatefulSetStrategy{Paused: true}
+			},
+			paused: func(obj runtime.Object) bool {
+				ru := obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.AdvancedStatefulSetTemplate.Spec.UpdateStrategy.RollingUpdate
+				return ru != nil && ru.Paused
+			},
+		},
+		{
+			name: "uniteddeployment with deployment subsets",
+			obj: func() runtime.Object {
+				ud := &kruiseappsv1alpha1.UnitedDeployment{}
+				ud.Spec.Template.DeploymentTemplate = &kruiseappsv1alpha1.DeploymentTemplateSpec{}
+				return ud
+			},
+			pause: func(obj runtime.Object) {
+				obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.DeploymentTemplate.Spec.Paused = true
+			},
+			paused: func(obj runtime.Object) bool {
+				return obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.DeploymentTemplate.Spec.Paused
+			},
+		},
+		{
+			name: "uniteddeployment with statefulset subsets",
+			obj: func() runtime.Object {
+				ud := &kruiseappsv1alpha1.UnitedDeployment{}
+				ud.Spec.Template.StatefulSetTemplate = &kruiseappsv1alpha1.StatefulSetTemplateSpec{}
+				return ud
+			},
+			unsupported: true,
+		},
+		{
+			name:        "uniteddeployment without subset template",
+			obj:         func() runtime.Object { return &kruiseappsv1alpha1.UnitedDeployment{} },
+			unsupported: true,
+		},
+		{
+			name:        "statefulset",
+			obj:         func() runtime.Object { return &appsv1.StatefulSet{} },
+			unsupported: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.unsupported {
+				_, err := defaultObjectPauser(test.obj())
+				assert.Error(t, err)
+				_, err = defaultObjectResumer(test.obj())
+				assert.Error(t, err)
+				return
+			}
+
+			// resuming an object which is not paused fails
+			_, err := defaultObjectResumer(test.obj())
+			assert.EqualError(t, err, "is not paused")
+
+			data, err := defaultObjectPauser(test.obj())
+			assert.NoError(t, err)
+			obj := test.obj()
+			assert.NoError(t, runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), data, obj))
+			assert.True(t, test.paused(obj))
+
+			// pausing an object which is already paused fails
+			obj = test.obj()
+			test.pause(obj)
+			_, err = defaultObjectPauser(obj)
+			assert.EqualError(t, err, "is already paused")
+
+			obj = test.obj()
+			test.pause(obj)
+			data, err = defaultObjectResumer(obj)
+			assert.NoError(t, err)
+			obj = test.obj()
+			assert.NoError(t, runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), data, obj))
+			assert.False(t, test.paused(obj))
+		})
+	}
+}
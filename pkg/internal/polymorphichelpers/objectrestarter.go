@@ -23,14 +23,23 @@ import (
 	"time"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
 	appsv1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/kubectl/pkg/scheme"
 )
 
+// RestartedAtAnnotation is patched onto the pod template of Kruise workloads to restart their pods.
+const RestartedAtAnnotation = "kubectl.kruise.io/restartedAt"
+
+// errUpdatedInPlace is returned for the workloads updating pods in place, which apply the change of
+// RestartedAtAnnotation to the pods without restarting any container.
+var errUpdatedInPlace = errors.New("can't restart pods updated in place, which apply the restartedAt annotation without restarting containers (run rollout restart --in-place instead)")
+
 func defaultObjectRestarter(obj runtime.Object) ([]byte, error) {
 	switch obj := obj.(type) {
 	case *extensionsv1beta1.Deployment:
@@ -114,15 +123,83 @@ func defaultObjectRestarter(obj runtime.Object) ([]byte, error) {
 		}
 		obj.Spec.Template.ObjectMeta.Annotations["kubectl.kubernetes.io/restartedAt"] = time.Now().Format(time.RFC3339)
 		return runtime.Encode(scheme.Codecs.LegacyCodec(appsv1beta2.SchemeGroupVersion), obj)
+
 	case *kruiseappsv1alpha1.CloneSet:
-		if obj.Spec.Template.ObjectMeta.Annotations == nil {
-			obj.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+		if cloneSetUpdatesInPlace(&obj.Spec) {
+			return nil, errUpdatedInPlace
+		}
+		setRestartedAt(&obj.Spec.Template)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1beta1.StatefulSet:
+		if advancedStatefulSetUpdatesInPlace(&obj.Spec) {
+			return nil, errUpdatedInPlace
+		}
+		setRestartedAt(&obj.Spec.Template)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1beta1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.StatefulSet:
+		if statefulSetUpdatesInPlace(&obj.Spec) {
+			return nil, errUpdatedInPlace
 		}
+		setRestartedAt(&obj.Spec.Template)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
 
-		obj.Spec.Template.ObjectMeta.Annotations["kubectl.kruise.io/restartedAt"] = time.Now().Format(time.RFC3339)
+	case *kruiseappsv1alpha1.DaemonSet:
+		setRestartedAt(&obj.Spec.Template)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		// every subset is created from the same subset template
+		template := &obj.Spec.Template
+		switch {
+		case template.StatefulSetTemplate != nil:
+			setRestartedAt(&template.StatefulSetTemplate.Spec.Template)
+		case template.AdvancedStatefulSetTemplate != nil:
+			if statefulSetUpdatesInPlace(&template.AdvancedStatefulSetTemplate.Spec) {
+				return nil, errors.New("can't restart uniteddeployment whose subsets update pods in place, which apply the restartedAt annotation without restarting containers (run rollout restart --in-place on the subsets instead)")
+			}
+			setRestartedAt(&template.AdvancedStatefulSetTemplate.Spec.Template)
+		case template.CloneSetTemplate != nil:
+			if cloneSetUpdatesInPlace(&template.CloneSetTemplate.Spec) {
+				return nil, errors.New("can't restart uniteddeployment whose subsets update pods in place, which apply the restartedAt annotation without restarting containers (run rollout restart --in-place on the subsets instead)")
+			}
+			setRestartedAt(&template.CloneSetTemplate.Spec.Template)
+		case template.DeploymentTemplate != nil:
+			if template.DeploymentTemplate.Spec.Paused {
+				return nil, errors.New("can't restart paused uniteddeployment (run rollout resume first)")
+			}
+			setRestartedAt(&template.DeploymentTemplate.Spec.Template)
+		default:
+			return nil, errors.New("uniteddeployment has no subset template to restart")
+		}
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
 
 	default:
 		return nil, fmt.Errorf("restarting is not supported")
 	}
 }
+
+func cloneSetUpdatesInPlace(spec *kruiseappsv1alpha1.CloneSetSpec) bool {
+	return spec.UpdateStrategy.Type == kruiseappsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType ||
+		spec.UpdateStrategy.Type == kruiseappsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType
+}
+
+func advancedStatefulSetUpdatesInPlace(spec *kruiseappsv1beta1.StatefulSetSpec) bool {
+	rollingUpdate := spec.UpdateStrategy.RollingUpdate
+	return rollingUpdate != nil && (rollingUpdate.PodUpdatePolicy == kruiseappsv1beta1.InPlaceIfPossiblePodUpdateStrategyType ||
+		rollingUpdate.PodUpdatePolicy == kruiseappsv1beta1.InPlaceOnlyPodUpdateStrategyType)
+}
+
+func statefulSetUpdatesInPlace(spec *kruiseappsv1alpha1.StatefulSetSpec) bool {
+	rollingUpdate := spec.UpdateStrategy.RollingUpdate
+	return rollingUpdate != nil && (rollingUpdate.PodUpdatePolicy == kruiseappsv1alpha1.InPlaceIfPossiblePodUpdateStrategyType ||
+		rollingUpdate.PodUpdatePolicy == kruiseappsv1alpha1.InPlaceOnlyPodUpdateStrategyType)
+}
+
+func setRestartedAt(template *corev1.PodTemplateSpec) {
+	if template.ObjectMeta.Annotations == nil {
+		template.ObjectMeta.Annotations = make(map[string]string)
+	}
+	template.ObjectMeta.Annotations[RestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+}
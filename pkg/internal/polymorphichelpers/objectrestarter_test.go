@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestDefaultObjectRestarter(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      func() runtime.Object
+		template func(obj runtime.Object) *corev1.PodTemplateSpec
+		wantErr  bool
+	}{
+		{
+			name: "cloneset",
+			obj:  func() runtime.Object { return &kruiseappsv1alpha1.CloneSet{} },
+			template: func(obj runtime.Object) *corev1.PodTemplateSpec {
+				return &obj.(*kruiseappsv1alpha1.CloneSet).Spec.Template
+			},
+		},
+		{
+			name: "advanced statefulset v1beta1",
+			obj:  func() runtime.Object { return &kruiseappsv1beta1.StatefulSet{} },
+			template: func(obj runtime.Object) *corev1.PodTemplateSpec {
+				return &obj.(*kruiseappsv1beta1.StatefulSet).Spec.Template
+			},
+		},
+		{
+			name: "advanced statefulset v1alpha1",
+			obj:  func() runtime.Object { return &kruiseappsv1alpha1.StatefulSet{} },
+			template: func(obj runtime.Object) *corev1.PodTemplateSpec {
+				return &obj.(*kruiseappsv1alpha1.StatefulSet).Spec.Template
+			},
+		},
+		{
+			name: "advanced daemonset",
+			obj:  func() runtime.Object { return &kruiseappsv1alpha1.DaemonSet{} },
+			template: func(obj runtime.Object) *corev1.PodTemplateSpec {
+				return &obj.(*kruiseappsv1alpha1.DaemonSet).Spec.Template
+			},
+		},
+		{
+			name: "uniteddeployment with cloneset subsets",
+			obj: func() runtime.Object {
+				ud := &kruiseappsv1alpha1.UnitedDeployment{}
+				ud.Spec.Template.CloneSetTemplate = &kruiseappsv1alpha1.CloneSetTemplateSpec{}
+				return ud
+			},
+			template: func(obj runtime.Object) *corev1.PodTemplateSpec {
+				return &obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.CloneSetTemplate.Spec.Template
+			},
+		},
+		{
+			name: "uniteddeployment with advanced statefulset subsets",
+			obj: func() runtime.Object {
+				ud := &kruiseappsv1alpha1.UnitedDeployment{}
+				ud.Spec.Template.AdvancedStatefulSetTemplate = &kruiseappsv1alpha1.AdvancedStatefulSetTemplateSpec{}
+				return ud
+			},
+			template: func(obj runtime.Object) *corev1.PodTemplateSpec {
+				return &obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.AdvancedStatefulSetTemplate.Spec.Template
+			},
+		},
+		{
+			name: "uniteddeployment with paused deployment subsets",
+			obj: func() runtime.Object {
+				ud := &kruiseappsv1alpha1.UnitedDeployment{}
+				ud.Spec.Template.DeploymentTemplate = &kruiseappsv1alpha1.DeploymentTemplateSpec{}
+				ud.Spec.Template.DeploymentTemplate.Spec.Paused = true
+				return ud
+			},
+			wantErr: true,
+		},
+		{
+			name: "cloneset updating pods in place",
+			obj: func() runtime.Object {
+				cs := &kruiseappsv1alpha1.CloneSet{}
+				cs.Spec.UpdateStrategy.Type = kruiseappsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType
+				return cs
+			},
+			wantErr: true,
+		},
+		{
+			name: "advanced statefulset v1beta1 updating pods in place",
+			obj: func() runtime.Object {
+				sts := &kruiseappsv1beta1.StatefulSet{}
+				sts.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{
+					PodUpdatePolicy: kruiseappsv1beta1.InPlaceOnlyPodUpdateStrategyType,
+				}
+				return sts
+			},
+			wantErr: true,
+		},
+		{
+			name: "advanced statefulset v1alpha1 updating pods in place",
+			obj: func() runtime.Object {
+				sts := &kruiseappsv1alpha1.StatefulSet{}
+				sts.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateStatefulSetStrategy{
+					PodUpdatePolicy: kruiseappsv1alpha1.InPlaceIfPossiblePodUpdateStrategyType,
+				}
+				return sts
+			},
+			wantErr: true,
+		},
+		{
+			name: "advanced statefulset recreating pods",
+			obj: func() runtime.Object {
+				sts := &kruiseappsv1beta1.StatefulSet{}
+				sts.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{
+					PodUpdatePolicy: kruiseappsv1beta1.RecreatePodUpdateStrategyType,
+				}
+				return sts
+			},
+			template: func(obj runtime.Object) *corev1.PodTemplateSpec {
+				return &obj.(*kruiseappsv1beta1.StatefulSet).Spec.Template
+			},
+		},
+		{
+			name: "uniteddeployment with cloneset subsets updating pods in place",
+			obj: func() runtime.Object {
+				ud := &kruiseappsv1alpha1.UnitedDeployment{}
+				ud.Spec.Template.CloneSetTemplate = &kruiseappsv1alpha1.CloneSetTemplateSpec{}
+				ud.Spec.Template.CloneSetTemplate.Spec.UpdateStrategy.Type = kruiseappsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType
+				return ud
+			},
+			wantErr: true,
+		},
+		{
+			name:    "uniteddeployment without subset template",
+			obj:     func() runtime.Object { return &kruiseappsv1alpha1.UnitedDeployment{} },
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := defaultObjectRestarter(test.obj())
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			restarted := test.obj()
+			assert.NoError(t, runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), data, restarted))
+			assert.NotEmpty(t, test.template(restarted).Annotations[RestartedAtAnnotation])
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 
 	appsv1 "k8s.io/api/apps/v1"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
@@ -65,6 +66,39 @@ func defaultObjectResumer(obj runtime.Object) ([]byte, error) {
 		}
 		obj.Spec.UpdateStrategy.Paused = false
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+	case *kruiseappsv1beta1.StatefulSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil || !obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return nil, errors.New("is not paused")
+		}
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = false
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1beta1.SchemeGroupVersion), obj)
+	case *kruiseappsv1alpha1.StatefulSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil || !obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return nil, errors.New("is not paused")
+		}
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = false
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+	case *kruiseappsv1alpha1.DaemonSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil || obj.Spec.UpdateStrategy.RollingUpdate.Paused == nil || !*obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return nil, errors.New("is not paused")
+		}
+		paused := false
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = &paused
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+	case *kruiseappsv1alpha1.SidecarSet:
+		if !obj.Spec.UpdateStrategy.Paused {
+			return nil, errors.New("is not paused")
+		}
+		obj.Spec.UpdateStrategy.Paused = false
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		changed, err := setUnitedDeploymentSubsetPaused(obj, false)
+		if err != nil {
+			return nil, err
+		} else if !changed {
+			return nil, errors.New("is not paused")
+		}
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
 
 	default:
 		return nil, fmt.Errorf("resuming is not supported")
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"encoding/json"
+
+	kruiseappspub "github.com/openkruise/kruise-api/apps/pub"
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PodUpdateInPlace means the pod has been updated to its revision in place.
+	PodUpdateInPlace = "InPlace"
+	// PodUpdateRecreate means the pod has been recreated with its revision.
+	PodUpdateRecreate = "Recreate"
+)
+
+// PodRolloutStatus is the rollout status of one pod of a workload.
+type PodRolloutStatus struct {
+	Name     string
+	Revision string
+	// Updated is true if the pod is at the update revision of the workload.
+	Updated bool
+	// UpdateType is how the pod came to its revision, either PodUpdateInPlace or PodUpdateRecreate,
+	// or empty if the pod has not been updated.
+	UpdateType string
+	// InPlaceUpdateReady is the status of the InPlaceUpdateReady condition, or empty if the pod has none.
+	InPlaceUpdateReady corev1.ConditionStatus
+	ReadyContainers    int
+	Containers         int
+	Restarts           int32
+}
+
+// CloneSetPodRolloutStatuses returns the rollout status of each given pod of the CloneSet.
+func CloneSetPodRolloutStatuses(cs *kruiseappsv1alpha1.CloneSet, pods []*corev1.Pod) []PodRolloutStatus {
+	statuses := make([]PodRolloutStatus, 0, len(pods))
+	for _, pod := range pods {
+		status := PodRolloutStatus{
+			Name:       pod.Name,
+			Revision:   pod.Labels[appsv1.ControllerRevisionHashLabelKey],
+			Containers: len(pod.Spec.Containers),
+		}
+		status.Updated = len(status.Revision) > 0 && status.Revision == cs.Status.UpdateRevision
+
+		if state, ok := inPlaceUpdateState(pod); ok && state.Revision == status.Revision {
+			status.UpdateType = PodUpdateInPlace
+		} else if status.Updated {
+			status.UpdateType = PodUpdateRecreate
+		}
+
+		for _, c := range pod.Status.Conditions {
+			if c.Type == kruiseappspub.InPlaceUpdateReady {
+				status.InPlaceUpdateReady = c.Status
+			}
+		}
+		for _, c := range pod.Status.ContainerStatuses {
+			if c.Ready {
+				status.ReadyContainers++
+			}
+			status.Restarts += c.RestartCount
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// inPlaceUpdateState returns the state recorded by the latest in-place update of the pod, if any.
+func inPlaceUpdateState(pod *corev1.Pod) (*kruiseappspub.InPlaceUpdateState, bool) {
+	value, ok := kruiseappspub.GetInPlaceUpdateState(pod)
+	if !ok {
+		return nil, false
+	}
+	state := &kruiseappspub.InPlaceUpdateState{}
+	if err := json.Unmarshal([]byte(value), state); err != nil {
+		return nil, false
+	}
+	return state, true
+}
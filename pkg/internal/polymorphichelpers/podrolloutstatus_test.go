@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"reflect"
+	"testing"
+
+	kruiseappspub "github.com/openkruise/kruise-api/apps/pub"
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCloneSetPodRolloutStatuses(t *testing.T) {
+	cs := &kruiseappsv1alpha1.CloneSet{Status: kruiseappsv1alpha1.CloneSetStatus{UpdateRevision: "foo-v2"}}
+	newPod := func(name, revision, inPlaceRevision string, inPlaceReady corev1.ConditionStatus) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{appsv1.ControllerRevisionHashLabelKey: revision}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", Ready: true, RestartCount: 1},
+				{Name: "sidecar", Ready: inPlaceReady != corev1.ConditionFalse, RestartCount: 2},
+			}},
+		}
+		if len(inPlaceRevision) > 0 {
+			pod.Annotations = map[string]string{kruiseappspub.InPlaceUpdateStateKey: `{"revision":"` + inPlaceRevision + `"}`}
+		}
+		if len(inPlaceReady) > 0 {
+			pod.Status.Conditions = []corev1.PodCondition{{Type: kruiseappspub.InPlaceUpdateReady, Status: inPlaceReady}}
+		}
+		return pod
+	}
+
+	pods := []*corev1.Pod{
+		newPod("foo-a", "foo-v1", "", corev1.ConditionTrue),
+		newPod("foo-b", "foo-v2", "foo-v2", corev1.ConditionFalse),
+		newPod("foo-c", "foo-v2", "foo-v1", ""),
+	}
+	expected := []PodRolloutStatus{
+		{Name: "foo-a", Revision: "foo-v1", InPlaceUpdateReady: corev1.ConditionTrue, ReadyContainers: 2, Containers: 2, Restarts: 3},
+		{Name: "foo-b", Revision: "foo-v2", Updated: true, UpdateType: PodUpdateInPlace, InPlaceUpdateReady: corev1.ConditionFalse, ReadyContainers: 1, Containers: 2, Restarts: 3},
+		{Name: "foo-c", Revision: "foo-v2", Updated: true, UpdateType: PodUpdateRecreate, ReadyContainers: 2, Containers: 2, Restarts: 3},
+	}
+	if got := CloneSetPodRolloutStatuses(cs, pods); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
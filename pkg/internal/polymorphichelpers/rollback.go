@@ -92,6 +92,20 @@ func (v *RollbackVisitor) VisitAdvancedStatefulSet(kind internalapps.GroupKindEl
 	v.result = &AdvancedStatefulSetRollbacker{cr: cr, c: c, k: v.clientset}
 }
 
+func (v *RollbackVisitor) VisitAdvancedDaemonSet(kind internalapps.GroupKindElement) {
+	mgr := internalapi.NewManager()
+	cr := mgr.GetAPIReader()
+	c := mgr.GetClient()
+	v.result = &AdvancedDaemonSetRollbacker{cr: cr, c: c, k: v.clientset}
+}
+
+func (v *RollbackVisitor) VisitUnitedDeployment(kind internalapps.GroupKindElement) {
+	mgr := internalapi.NewManager()
+	cr := mgr.GetAPIReader()
+	c := mgr.GetClient()
+	v.result = &UnitedDeploymentRollbacker{cr: cr, c: c, k: v.clientset}
+}
+
 // RollbackerFor returns an implementation of Rollbacker interface for the given schema kind
 func RollbackerFor(kind schema.GroupKind, c kubernetes.Interface) (Rollbacker, error) {
 	elem := internalapps.GroupKindElement(kind)
@@ -141,7 +155,14 @@ func (r *DeploymentRollbacker) Rollback(obj runtime.Object, updatedAnnotations m
 		return "", err
 	}
 	if dryRunStrategy == cmdutil.DryRunClient {
-		return printTemplate(&rsForRevision.Spec.Template)
+		// the hash label is removed from the template when patching back into the deployment
+		template := rsForRevision.Spec.Template.DeepCopy()
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		revision, err := deploymentutil.Revision(rsForRevision)
+		if err != nil {
+			return "", err
+		}
+		return printPodTemplateDiff(&deployment.Spec.Template, template, revision)
 	}
 	if deployment.Spec.Paused {
 		return "", fmt.Errorf("you cannot rollback a paused deployment; resume it first with 'kubectl rollout resume deployment/%s' and try again", name)
@@ -306,7 +327,7 @@ func (r *DaemonSetRollbacker) Rollback(obj runtime.Object, updatedAnnotations ma
 		if err != nil {
 			return "", err
 		}
-		return printPodTemplate(&appliedDS.Spec.Template)
+		return printPodTemplateDiff(&ds.Spec.Template, &appliedDS.Spec.Template, toHistory.Revision)
 	}
 
 	// Skip if the revision already matches current DaemonSet
@@ -397,7 +418,7 @@ func (r *StatefulSetRollbacker) Rollback(obj runtime.Object, updatedAnnotations
 		if err != nil {
 			return "", err
 		}
-		return printPodTemplate(&appliedSS.Spec.Template)
+		return printPodTemplateDiff(&sts.Spec.Template, &appliedSS.Spec.Template, toHistory.Revision)
 	}
 
 	// Skip if the revision already matches current StatefulSet
@@ -456,7 +477,7 @@ func (r *CloneSetRollbacker) Rollback(obj runtime.Object,
 		if err != nil {
 			return "", err
 		}
-		return printPodTemplate(&appliedSS.Spec.Template)
+		return printPodTemplateDiff(&cs.Spec.Template, &appliedSS.Spec.Template, toHistory.Revision)
 	}
 
 	// Skip if the revision already matches current CloneSet
@@ -468,17 +489,21 @@ func (r *CloneSetRollbacker) Rollback(obj runtime.Object,
 		return fmt.Sprintf("%s (current template already matches revision %d)", rollbackSkipped, toRevision), nil
 	}
 
-	// Restore revision
-	if dryRunStrategy == cmdutil.DryRunServer {
-		if err = r.c.Patch(context.TODO(), cs, client.RawPatch(types.MergePatchType,
-			toHistory.Data.Raw), client.DryRunAll); err != nil {
-			return "", fmt.Errorf("failed restoring revision %d: %v", toRevision, err)
-		}
+	annotations := rollbackAnnotations(toHistory, updatedAnnotations)
+	patch, err := addAnnotationsToPatch(toHistory.Data.Raw, annotations)
+	if err != nil {
+		return "", fmt.Errorf("failed restoring revision %d: %v", toRevision, err)
 	}
-	if err = r.c.Patch(context.TODO(), cs, client.RawPatch(types.MergePatchType,
-		toHistory.Data.Raw)); err != nil {
+
+	// Restore revision
+	if err = r.c.Patch(context.TODO(), cs, client.RawPatch(types.MergePatchType, patch), rollbackPatchOptions(dryRunStrategy)...); err != nil {
 		return "", fmt.Errorf("failed restoring revision %d: %v", toRevision, err)
 	}
+	if dryRunStrategy != cmdutil.DryRunServer {
+		if err = recordRollbackChangeCause(r.k, toHistory, annotations); err != nil {
+			return "", err
+		}
+	}
 
 	return rollbackSuccess, nil
 }
@@ -516,7 +541,7 @@ func (r *AdvancedStatefulSetRollbacker) Rollback(obj runtime.Object,
 		if err != nil {
 			return "", err
 		}
-		return printPodTemplate(&appliedSS.Spec.Template)
+		return printPodTemplateDiff(&asts.Spec.Template, &appliedSS.Spec.Template, toHistory.Revision)
 	}
 	// Skip if the revision already matches current CloneSet
 	done, err := astsMatch(asts, toHistory)
@@ -527,21 +552,173 @@ func (r *AdvancedStatefulSetRollbacker) Rollback(obj runtime.Object,
 		return fmt.Sprintf("%s (current template already matches revision %d)", rollbackSkipped, toRevision), nil
 	}
 
+	annotations := rollbackAnnotations(toHistory, updatedAnnotations)
+	patch, err := addAnnotationsToPatch(toHistory.Data.Raw, annotations)
+	if err != nil {
+		return "", fmt.Errorf("failed restoring revision %d: %v", toRevision, err)
+	}
+
 	// Restore revision
-	if dryRunStrategy == cmdutil.DryRunServer {
-		if err = r.c.Patch(context.TODO(), asts, client.RawPatch(types.MergePatchType,
-			toHistory.Data.Raw), client.DryRunAll); err != nil {
-			return "", fmt.Errorf("failed restoring revision %d: %v", toRevision, err)
+	if err = r.c.Patch(context.TODO(), asts, client.RawPatch(types.MergePatchType, patch), rollbackPatchOptions(dryRunStrategy)...); err != nil {
+		return "", fmt.Errorf("failed restoring revision %d: %v", toRevision, err)
+	}
+	if dryRunStrategy != cmdutil.DryRunServer {
+		if err = recordRollbackChangeCause(r.k, toHistory, annotations); err != nil {
+			return "", err
+		}
+	}
+
+	return rollbackSuccess, nil
+}
+
+type AdvancedDaemonSetRollbacker struct {
+	cr client.Reader
+	c  client.Client
+	k  kubernetes.Interface
+}
+
+func (r *AdvancedDaemonSetRollbacker) Rollback(obj runtime.Object,
+	updatedAnnotations map[string]string,
+	toRevision int64,
+	dryRunStrategy cmdutil.DryRunStrategy) (string, error) {
+	if toRevision < 0 {
+		return "", revisionNotFoundErr(toRevision)
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to create accessor for kind %v: %s", obj.GetObjectKind(), err.Error())
+	}
+	ds, history, err := advancedDaemonSetHistory(r.k.AppsV1(), r.cr, accessor.GetNamespace(), accessor.GetName())
+	if err != nil {
+		return "", err
+	}
+	if toRevision == 0 && len(history) <= 1 {
+		return "", fmt.Errorf("no last revision to roll back to")
+	}
+	toHistory := findHistory(toRevision, history)
+	if toHistory == nil {
+		return "", revisionNotFoundErr(toRevision)
+	}
+
+	appliedDS, err := applyAdvancedDaemonSetHistory(ds, toHistory)
+	if err != nil {
+		return "", err
+	}
+	if dryRunStrategy == cmdutil.DryRunClient {
+		return printPodTemplateDiff(&ds.Spec.Template, &appliedDS.Spec.Template, toHistory.Revision)
+	}
+
+	// Skip if the revision already matches current Advanced DaemonSet
+	done, err := advancedDaemonSetMatch(ds, toHistory)
+	if err != nil {
+		return "", err
+	}
+	if done {
+		return fmt.Sprintf("%s (current template already matches revision %d)", rollbackSkipped, toRevision), nil
+	}
+
+	// Restore revision, custom resources do not support strategic merge patch,
+	// so the template restored locally is sent as a merge patch.
+	annotations := rollbackAnnotations(toHistory, updatedAnnotations)
+	if len(annotations) > 0 && appliedDS.Annotations == nil {
+		appliedDS.Annotations = make(map[string]string)
+	}
+	for k, v := range annotations {
+		appliedDS.Annotations[k] = v
+	}
+	if err = r.c.Patch(context.TODO(), appliedDS, client.MergeFrom(ds), rollbackPatchOptions(dryRunStrategy)...); err != nil {
+		return "", fmt.Errorf("failed restoring revision %d: %v", toRevision, err)
+	}
+	if dryRunStrategy != cmdutil.DryRunServer {
+		if err = recordRollbackChangeCause(r.k, toHistory, annotations); err != nil {
+			return "", err
+		}
+	}
+
+	return rollbackSuccess, nil
+}
+
+type UnitedDeploymentRollbacker struct {
+	cr client.Reader
+	c  client.Client
+	k  kubernetes.Interface
+}
+
+func (r *UnitedDeploymentRollbacker) Rollback(obj runtime.Object,
+	updatedAnnotations map[string]string,
+	toRevision int64,
+	dryRunStrategy cmdutil.DryRunStrategy) (string, error) {
+	if toRevision < 0 {
+		return "", revisionNotFoundErr(toRevision)
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to create accessor for kind %v: %s", obj.GetObjectKind(), err.Error())
+	}
+	ud, history, err := unitedDeploymentHistory(r.k.AppsV1(), r.cr, accessor.GetNamespace(), accessor.GetName())
+	if err != nil {
+		return "", err
+	}
+	if toRevision == 0 && len(history) <= 1 {
+		return "", fmt.Errorf("no last revision to roll back to")
+	}
+	toHistory := findHistory(toRevision, history)
+	if toHistory == nil {
+		return "", revisionNotFoundErr(toRevision)
+	}
+
+	appliedUD, err := applyUnitedDeploymentHistory(ud, toHistory)
+	if err != nil {
+		return "", err
+	}
+	if dryRunStrategy == cmdutil.DryRunClient {
+		current, err := UnitedDeploymentPodTemplate(ud)
+		if err != nil {
+			return "", err
 		}
+		template, err := UnitedDeploymentPodTemplate(appliedUD)
+		if err != nil {
+			return "", err
+		}
+		return printPodTemplateDiff(current, template, toHistory.Revision)
+	}
+
+	// Skip if the revision already matches current UnitedDeployment
+	done, err := unitedDeploymentMatch(ud, toHistory)
+	if err != nil {
+		return "", err
+	}
+	if done {
+		return fmt.Sprintf("%s (current template already matches revision %d)", rollbackSkipped, toRevision), nil
 	}
-	if err = r.c.Patch(context.TODO(), asts, client.RawPatch(types.MergePatchType,
-		toHistory.Data.Raw)); err != nil {
+
+	// Restore revision, the whole subset template is replaced
+	annotations := rollbackAnnotations(toHistory, updatedAnnotations)
+	if len(annotations) > 0 && appliedUD.Annotations == nil {
+		appliedUD.Annotations = make(map[string]string)
+	}
+	for k, v := range annotations {
+		appliedUD.Annotations[k] = v
+	}
+	if err = r.c.Patch(context.TODO(), appliedUD, client.MergeFrom(ud), rollbackPatchOptions(dryRunStrategy)...); err != nil {
 		return "", fmt.Errorf("failed restoring revision %d: %v", toRevision, err)
 	}
+	if dryRunStrategy != cmdutil.DryRunServer {
+		if err = recordRollbackChangeCause(r.k, toHistory, annotations); err != nil {
+			return "", err
+		}
+	}
 
 	return rollbackSuccess, nil
 }
 
+func rollbackPatchOptions(dryRunStrategy cmdutil.DryRunStrategy) []client.PatchOption {
+	if dryRunStrategy == cmdutil.DryRunServer {
+		return []client.PatchOption{client.DryRunAll}
+	}
+	return nil
+}
+
 var appsCodec = scheme.Codecs.LegacyCodec(appsv1.SchemeGroupVersion)
 
 // applyRevision returns a new StatefulSet constructed by restoring the state in revision to set. If the returned error
@@ -592,7 +769,7 @@ func applyAdvancedStatefulSetRevision(asts *kruiseappsv1beta1.StatefulSet,
 	if err != nil {
 		return nil, err
 	}
-	return nil, err
+	return result, nil
 }
 
 // statefulsetMatch check if the given StatefulSet's template matches the template stored in the given history.
@@ -613,6 +790,24 @@ func astsMatch(asts *kruiseappsv1beta1.StatefulSet, history *appsv1.ControllerRe
 	return bytes.Equal(patch, history.Data.Raw), nil
 }
 
+// advancedDaemonSetMatch check if the given Advanced DaemonSet's template matches the template stored in the given history.
+func advancedDaemonSetMatch(ds *kruiseappsv1alpha1.DaemonSet, history *appsv1.ControllerRevision) (bool, error) {
+	patch, err := getTemplatePatch(ds)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(patch, history.Data.Raw), nil
+}
+
+// unitedDeploymentMatch check if the given UnitedDeployment's subset template matches the template stored in the given history.
+func unitedDeploymentMatch(ud *kruiseappsv1alpha1.UnitedDeployment, history *appsv1.ControllerRevision) (bool, error) {
+	patch, err := getTemplatePatch(ud)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(patch, history.Data.Raw), nil
+}
+
 // cloneSetMatch check if the given CloneSet's template matches the template stored in the given history.
 func cloneSetMatch(cs *kruiseappsv1alpha1.CloneSet, history *appsv1.ControllerRevision) (bool, error) {
 	patch, err := getCloneSetPatch(cs)
@@ -691,6 +886,31 @@ func getAdvancedStatefulSetPatch(asts *kruiseappsv1beta1.StatefulSet) ([]byte, e
 	return patch, err
 }
 
+// getTemplatePatch returns a strategic merge patch that replaces spec.template of the given Kruise workload,
+// in the same form as the data recorded in its ControllerRevisions.
+func getTemplatePatch(obj runtime.Object) ([]byte, error) {
+	str, err := runtime.Encode(kruiseAppsCodec, obj)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(str, &raw); err != nil {
+		return nil, err
+	}
+	spec, ok := raw["spec"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no spec found in %T", obj)
+	}
+	template, ok := spec["template"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no spec.template found in %T", obj)
+	}
+	template["$patch"] = "replace"
+	return json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"template": template},
+	})
+}
+
 // findHistory returns a controllerrevision of a specific revision from the given controllerrevisions.
 // It returns nil if no such controllerrevision exists.
 // If toRevision is 0, the last previously used history is returned.
@@ -717,13 +937,13 @@ func findHistory(toRevision int64, allHistory []*appsv1.ControllerRevision) *app
 	return toHistory
 }
 
-// printPodTemplate converts a given pod template into a human-readable string.
-func printPodTemplate(specTemplate *corev1.PodTemplateSpec) (string, error) {
-	podSpec, err := printTemplate(specTemplate)
+// printPodTemplateDiff returns the diff between the current pod template and the one of the revision to roll back to.
+func printPodTemplateDiff(current, target *corev1.PodTemplateSpec, revision int64) (string, error) {
+	diff, err := DiffPodTemplates("current", fmt.Sprintf("revision %d", revision), current, target)
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("will roll back to %s", podSpec), nil
+	return fmt.Sprintf("will roll back to revision %d:\n%s", revision, diff), nil
 }
 
 func revisionNotFoundErr(r int64) error {
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+func newTestTemplate(image string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+	}
+}
+
+// newTestRevision returns a ControllerRevision owned by owner, with the template patch of obj as its data.
+func newTestRevision(t *testing.T, owner metav1.Object, kind string, obj runtime.Object, revision int64) *appsv1.ControllerRevision {
+	patch, err := getTemplatePatch(obj)
+	assert.NoError(t, err)
+	isController := true
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       owner.GetNamespace(),
+			Name:            fmt.Sprintf("%s-%d", owner.GetName(), revision),
+			Labels:          map[string]string{"app": "web"},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps.kruise.io/v1alpha1", Kind: kind, Name: owner.GetName(), UID: owner.GetUID(), Controller: &isController}},
+		},
+		Data:     runtime.RawExtension{Raw: patch},
+		Revision: revision,
+	}
+}
+
+func newTestAdvancedDaemonSet(image string) *kruiseappsv1alpha1.DaemonSet {
+	return &kruiseappsv1alpha1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "web-uid"},
+		Spec:       kruiseappsv1alpha1.DaemonSetSpec{Selector: testSelector, Template: newTestTemplate(image)},
+	}
+}
+
+func newTestUnitedDeployment(image string) *kruiseappsv1alpha1.UnitedDeployment {
+	ud := &kruiseappsv1alpha1.UnitedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "web-uid"},
+		Spec:       kruiseappsv1alpha1.UnitedDeploymentSpec{Selector: testSelector},
+	}
+	ud.Spec.Template.CloneSetTemplate = &kruiseappsv1alpha1.CloneSetTemplateSpec{}
+	ud.Spec.Template.CloneSetTemplate.Spec.Template = newTestTemplate(image)
+	return ud
+}
+
+func TestAdvancedDaemonSetHistoryAndRollback(t *testing.T) {
+	ds := newTestAdvancedDaemonSet("nginx:1.20")
+	k := fake.NewSimpleClientset(
+		newTestRevision(t, ds, "DaemonSet", newTestAdvancedDaemonSet("nginx:1.19"), 1),
+		newTestRevision(t, ds, "DaemonSet", ds, 2))
+	c := crfake.NewFakeClientWithScheme(api.GetScheme(), ds)
+
+	viewer := &AdvancedDaemonSetHistoryViewer{c: c, k: k}
+	revisions, current, err := viewer.GetHistory("default", "web")
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "nginx:1.19", revisions[1].Template.Spec.Containers[0].Image)
+	assert.Equal(t, "nginx:1.20", current.Spec.Containers[0].Image)
+	detail, err := viewer.ViewHistory("default", "web", 1)
+	assert.NoError(t, err)
+	assert.Contains(t, detail, "nginx:1.19")
+
+	rollbacker := &AdvancedDaemonSetRollbacker{cr: c, c: c, k: k}
+	diff, err := rollbacker.Rollback(ds, nil, 0, cmdutil.DryRunClient)
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "nginx:1.19")
+
+	msg, err := rollbacker.Rollback(ds, nil, 0, cmdutil.DryRunNone)
+	assert.NoError(t, err)
+	assert.Equal(t, rollbackSuccess, msg)
+	rolledBack := &kruiseappsv1alpha1.DaemonSet{}
+	assert.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "web"}, rolledBack))
+	assert.Equal(t, "nginx:1.19", rolledBack.Spec.Template.Spec.Containers[0].Image)
+
+	msg, err = rollbacker.Rollback(ds, nil, 1, cmdutil.DryRunNone)
+	assert.NoError(t, err)
+	assert.Contains(t, msg, rollbackSkipped)
+
+	_, err = rollbacker.Rollback(ds, nil, 3, cmdutil.DryRunNone)
+	assert.Error(t, err)
+}
+
+func TestUnitedDeploymentHistoryAndRollback(t *testing.T) {
+	ud := newTestUnitedDeployment("nginx:1.20")
+	k := fake.NewSimpleClientset(
+		newTestRevision(t, ud, "UnitedDeployment", newTestUnitedDeployment("nginx:1.19"), 1),
+		newTestRevision(t, ud, "UnitedDeployment", ud, 2))
+	c := crfake.NewFakeClientWithScheme(api.GetScheme(), ud)
+
+	viewer := &UnitedDeploymentHistoryViewer{c: c, k: k}
+	revisions, current, err := viewer.GetHistory("default", "web")
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "nginx:1.19", revisions[1].Template.Spec.Containers[0].Image)
+	assert.Equal(t, "nginx:1.20", current.Spec.Containers[0].Image)
+	detail, err := viewer.ViewHistory("default", "web", 1)
+	assert.NoError(t, err)
+	assert.Contains(t, detail, "nginx:1.19")
+
+	rollbacker := &UnitedDeploymentRollbacker{cr: c, c: c, k: k}
+	diff, err := rollbacker.Rollback(ud, nil, 1, cmdutil.DryRunClient)
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "nginx:1.19")
+
+	msg, err := rollbacker.Rollback(ud, nil, 1, cmdutil.DryRunNone)
+	assert.NoError(t, err)
+	assert.Equal(t, rollbackSuccess, msg)
+	rolledBack := &kruiseappsv1alpha1.UnitedDeployment{}
+	assert.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "web"}, rolledBack))
+	assert.Equal(t, "nginx:1.19", rolledBack.Spec.Template.CloneSetTemplate.Spec.Template.Spec.Containers[0].Image)
+
+	msg, err = rollbacker.Rollback(ud, nil, 1, cmdutil.DryRunNone)
+	assert.NoError(t, err)
+	assert.Contains(t, msg, rollbackSkipped)
+}
+
+func TestGetTemplatePatch(t *testing.T) {
+	patch, err := getTemplatePatch(newTestAdvancedDaemonSet("nginx:1.19"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(patch), `"$patch":"replace"`)
+	assert.NotContains(t, string(patch), "selector")
+
+	// sidecarsets have no spec.template
+	_, err = getTemplatePatch(&kruiseappsv1alpha1.SidecarSet{})
+	assert.Error(t, err)
+}
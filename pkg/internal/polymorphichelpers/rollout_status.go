@@ -19,14 +19,19 @@ package polymorphichelpers
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 
 	appsv1 "k8s.io/api/apps/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	deploymentutil "k8s.io/kubectl/pkg/util/deployment"
 )
 
@@ -51,6 +56,14 @@ func StatusViewerFor(kind schema.GroupKind) (StatusViewer, error) {
 
 	case kruiseappsv1beta1.SchemeGroupVersion.WithKind("StatefulSet").GroupKind():
 		return &AdvancedStatefulSetStatusViewer{}, nil
+	case kruiseappsv1alpha1.SchemeGroupVersion.WithKind("DaemonSet").GroupKind():
+		return &AdvancedDaemonSetStatusViewer{}, nil
+	case kruiseappsv1alpha1.SchemeGroupVersion.WithKind("UnitedDeployment").GroupKind():
+		return &UnitedDeploymentStatusViewer{}, nil
+	case kruiseappsv1alpha1.SchemeGroupVersion.WithKind("SidecarSet").GroupKind():
+		return &SidecarSetStatusViewer{}, nil
+	case kruiseappsv1alpha1.SchemeGroupVersion.WithKind("BroadcastJob").GroupKind():
+		return &BroadcastJobStatusViewer{}, nil
 	}
 	return nil, fmt.Errorf("no status viewer has been implemented for %v", kind)
 }
@@ -70,6 +83,23 @@ type CloneSetStatusViewer struct{}
 // AdvancedStatefulSetStatusViewer  implements the StatusViewer interface
 type AdvancedStatefulSetStatusViewer struct{}
 
+// AdvancedDaemonSetStatusViewer implements the StatusViewer interface
+type AdvancedDaemonSetStatusViewer struct {
+	// CountSelectedNodes returns the number of nodes running pods of the daemonset which match the selector.
+	// It is used for rolling updates limited by rollingUpdate.selector, which are only considered done
+	// once any pod is updated when it is not set.
+	CountSelectedNodes func(daemon *kruiseappsv1alpha1.DaemonSet, selector labels.Selector) (int32, error)
+}
+
+// UnitedDeploymentStatusViewer implements the StatusViewer interface
+type UnitedDeploymentStatusViewer struct{}
+
+// SidecarSetStatusViewer implements the StatusViewer interface
+type SidecarSetStatusViewer struct{}
+
+// BroadcastJobStatusViewer implements the StatusViewer interface
+type BroadcastJobStatusViewer struct{}
+
 // Status returns a message describing deployment status, and a bool value indicating if the status is considered done.
 func (s *DeploymentStatusViewer) Status(obj runtime.Unstructured, revision int64) (string, bool, error) {
 	deployment := &appsv1.Deployment{}
@@ -174,25 +204,39 @@ func (s *CloneSetStatusViewer) Status(obj runtime.Unstructured, revision int64)
 		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, cs, err)
 	}
 
-	// check InPlaceOnly and InPlacePossible UpdateStrategy
-	if cs.Spec.UpdateStrategy.Type == kruiseappsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType ||
-		cs.Spec.UpdateStrategy.Type == kruiseappsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType {
-		if cs.Spec.Replicas != nil && cs.Spec.UpdateStrategy.Partition != nil {
-			if cs.Status.UpdatedReplicas < (*cs.Spec.Replicas - cs.Spec.UpdateStrategy.Partition.IntVal) {
-				return fmt.Sprintf("Waiting for partitioned roll out to finish: %d out of %d new pods have been updated...\n",
-					cs.Status.UpdatedReplicas, *cs.Spec.Replicas-cs.Spec.UpdateStrategy.Partition.IntVal), false, nil
+	if cs.Status.ObservedGeneration == 0 || cs.Generation > cs.Status.ObservedGeneration {
+		return "Waiting for CloneSet spec update to be observed...\n", false, nil
+	}
 
-			}
-		}
+	// the partition applies to every update strategy, including ReCreate
+	partition, err := CloneSetPartition(cs)
+	if err != nil {
+		return "", false, err
 	}
+	replicas := getReplicas(cs.Spec.Replicas)
+	desiredUpdated := replicas - partition
+	currentReplicas := cs.Status.Replicas - cs.Status.UpdatedReplicas
 
-	if cs.Status.ObservedGeneration == 0 || cs.Generation > cs.Status.ObservedGeneration {
-		return "Waiting for CloneSet spec update to be observed...\n", false, nil
+	if cs.Status.UpdatedReplicas < desiredUpdated {
+		return fmt.Sprintf("Waiting for CloneSet %q rollout to finish: %d out of %d new pods have been updated (%d updated ready, %d current)...\n",
+			cs.Name, cs.Status.UpdatedReplicas, desiredUpdated, cs.Status.UpdatedReadyReplicas, currentReplicas), false, nil
+	}
+	if cs.Status.UpdatedReadyReplicas < desiredUpdated {
+		return fmt.Sprintf("Waiting for CloneSet %q rollout to finish: %d of %d updated pods are ready (%d current)...\n",
+			cs.Name, cs.Status.UpdatedReadyReplicas, desiredUpdated, currentReplicas), false, nil
 	}
-	if cs.Spec.Replicas != nil && cs.Status.ReadyReplicas < *cs.Spec.Replicas {
-		return fmt.Sprintf("Waiting for %d pods to be ready...\n", *cs.Spec.Replicas-cs.Status.ReadyReplicas), false, nil
+	if cs.Status.Replicas > replicas {
+		return fmt.Sprintf("Waiting for CloneSet %q rollout to finish: %d old pods are pending termination...\n",
+			cs.Name, cs.Status.Replicas-replicas), false, nil
+	}
+	if cs.Status.ReadyReplicas < replicas {
+		return fmt.Sprintf("Waiting for CloneSet %q rollout to finish: %d pods to be ready...\n", cs.Name, replicas-cs.Status.ReadyReplicas), false, nil
 	}
 
+	if partition > 0 {
+		return fmt.Sprintf("CloneSet %q partitioned roll out complete: %d new pods have been updated to revision %s, %d pods at current revision %s...\n",
+			cs.Name, cs.Status.UpdatedReplicas, cs.Status.UpdateRevision, currentReplicas, cs.Status.CurrentRevision), true, nil
+	}
 	return fmt.Sprintf("CloneSet rolling update complete %d pods at revision %s...\n", cs.Status.AvailableReplicas, cs.Status.UpdateRevision), true, nil
 }
 
@@ -224,3 +268,184 @@ func (s *AdvancedStatefulSetStatusViewer) Status(obj runtime.Unstructured, revis
 	return fmt.Sprintf("Advanced StatefulSet rolling update complete %d pods at revision %s...\n", asts.Status.AvailableReplicas, asts.Status.UpdateRevision), true, nil
 
 }
+
+// Status returns a message describing advanced daemonset status, and a bool value indicating if the status is considered done.
+func (s *AdvancedDaemonSetStatusViewer) Status(obj runtime.Unstructured, revision int64) (string, bool, error) {
+	daemon := &kruiseappsv1alpha1.DaemonSet{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), daemon)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, daemon, err)
+	}
+
+	if daemon.Spec.UpdateStrategy.Type != kruiseappsv1alpha1.RollingUpdateDaemonSetStrategyType {
+		return "", true, fmt.Errorf("rollout status is only available for %s strategy type", kruiseappsv1alpha1.RollingUpdateDaemonSetStrategyType)
+	}
+	if daemon.Status.ObservedGeneration == 0 || daemon.Generation > daemon.Status.ObservedGeneration {
+		return "Waiting for Advanced DaemonSet spec update to be observed...\n", false, nil
+	}
+
+	rollingUpdate := daemon.Spec.UpdateStrategy.RollingUpdate
+	var partition int32
+	if rollingUpdate != nil && rollingUpdate.Partition != nil {
+		partition = *rollingUpdate.Partition
+	}
+	desiredUpdated := daemon.Status.DesiredNumberScheduled - partition
+
+	// only pods on nodes matching the selector will be updated, and how many nodes match is not in status
+	if rollingUpdate != nil && rollingUpdate.Selector != nil {
+		if s.CountSelectedNodes == nil {
+			return advancedDaemonSetSelectedStatus(daemon, desiredUpdated > 0)
+		}
+		selector, err := metav1.LabelSelectorAsSelector(rollingUpdate.Selector)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid rollingUpdate.selector: %v", err)
+		}
+		selected, err := s.CountSelectedNodes(daemon, selector)
+		if err != nil {
+			return "", false, err
+		}
+		desiredUpdated = selected - partition
+	}
+	if desiredUpdated < 0 {
+		desiredUpdated = 0
+	}
+
+	if daemon.Status.UpdatedNumberScheduled < desiredUpdated {
+		return fmt.Sprintf("Waiting for Advanced DaemonSet %q rollout to finish: %d out of %d new pods have been updated...\n",
+			daemon.Name, daemon.Status.UpdatedNumberScheduled, desiredUpdated), false, nil
+	}
+	if daemon.Status.NumberAvailable < daemon.Status.DesiredNumberScheduled {
+		return fmt.Sprintf("Waiting for Advanced DaemonSet %q rollout to finish: %d of %d pods are available...\n",
+			daemon.Name, daemon.Status.NumberAvailable, daemon.Status.DesiredNumberScheduled), false, nil
+	}
+	if desiredUpdated < daemon.Status.DesiredNumberScheduled {
+		return fmt.Sprintf("Advanced DaemonSet %q partitioned roll out complete: %d new pods have been updated...\n", daemon.Name, daemon.Status.UpdatedNumberScheduled), true, nil
+	}
+	return fmt.Sprintf("Advanced DaemonSet %q successfully rolled out\n", daemon.Name), true, nil
+}
+
+// advancedDaemonSetSelectedStatus returns the status of a rolling update limited by rollingUpdate.selector
+// without knowing how many pods will be updated: it is done once any pod is updated, if expected, and all pods are available.
+func advancedDaemonSetSelectedStatus(daemon *kruiseappsv1alpha1.DaemonSet, expectUpdated bool) (string, bool, error) {
+	if (expectUpdated && daemon.Status.UpdatedNumberScheduled == 0) || daemon.Status.NumberAvailable < daemon.Status.DesiredNumberScheduled {
+		return fmt.Sprintf("Waiting for Advanced DaemonSet %q rollout to finish: %d pods on selected nodes have been updated, %d of %d pods are available...\n",
+			daemon.Name, daemon.Status.UpdatedNumberScheduled, daemon.Status.NumberAvailable, daemon.Status.DesiredNumberScheduled), false, nil
+	}
+	return fmt.Sprintf("Advanced DaemonSet %q rolled out on selected nodes: %d pods have been updated\n", daemon.Name, daemon.Status.UpdatedNumberScheduled), true, nil
+}
+
+// Status returns a message describing uniteddeployment status, and a bool value indicating if the status is considered done.
+func (s *UnitedDeploymentStatusViewer) Status(obj runtime.Unstructured, revision int64) (string, bool, error) {
+	ud := &kruiseappsv1alpha1.UnitedDeployment{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), ud)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, ud, err)
+	}
+
+	if ud.Status.ObservedGeneration == 0 || ud.Generation > ud.Status.ObservedGeneration {
+		return "Waiting for UnitedDeployment spec update to be observed...\n", false, nil
+	}
+
+	var partitions map[string]int32
+	if ud.Status.UpdateStatus != nil {
+		partitions = ud.Status.UpdateStatus.CurrentPartitions
+	}
+
+	// per-subset targets, subsets sorted by name
+	var subsetNames []string
+	for name := range ud.Status.SubsetReplicas {
+		subsetNames = append(subsetNames, name)
+	}
+	sort.Strings(subsetNames)
+	var subsetMessages []string
+	var desiredUpdated int32
+	for _, name := range subsetNames {
+		replicas := ud.Status.SubsetReplicas[name]
+		partition := partitions[name]
+		if partition > replicas {
+			partition = replicas
+		}
+		desiredUpdated += replicas - partition
+		subsetMessages = append(subsetMessages, fmt.Sprintf("  subset %q: %d replicas, %d to be updated\n", name, replicas, replicas-partition))
+	}
+	subsets := strings.Join(subsetMessages, "")
+
+	if ud.Spec.Replicas != nil && ud.Status.ReadyReplicas < *ud.Spec.Replicas {
+		return fmt.Sprintf("Waiting for %d pods to be ready...\n%s", *ud.Spec.Replicas-ud.Status.ReadyReplicas, subsets), false, nil
+	}
+	if ud.Status.UpdatedReplicas < desiredUpdated {
+		return fmt.Sprintf("Waiting for UnitedDeployment %q rollout to finish: %d out of %d new pods have been updated...\n%s",
+			ud.Name, ud.Status.UpdatedReplicas, desiredUpdated, subsets), false, nil
+	}
+	if ud.Status.UpdatedReadyReplicas < desiredUpdated {
+		return fmt.Sprintf("Waiting for UnitedDeployment %q rollout to finish: %d of %d updated pods are ready...\n%s",
+			ud.Name, ud.Status.UpdatedReadyReplicas, desiredUpdated, subsets), false, nil
+	}
+
+	updatedRevision := ud.Status.CurrentRevision
+	if ud.Status.UpdateStatus != nil && len(ud.Status.UpdateStatus.UpdatedRevision) > 0 {
+		updatedRevision = ud.Status.UpdateStatus.UpdatedRevision
+	}
+	return fmt.Sprintf("UnitedDeployment rolling update complete %d pods at revision %s...\n%s", ud.Status.UpdatedReplicas, updatedRevision, subsets), true, nil
+}
+
+// Status returns a message describing sidecarset status, and a bool value indicating if the status is considered done.
+func (s *SidecarSetStatusViewer) Status(obj runtime.Unstructured, revision int64) (string, bool, error) {
+	sidecarSet := &kruiseappsv1alpha1.SidecarSet{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), sidecarSet)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, sidecarSet, err)
+	}
+
+	if sidecarSet.Status.ObservedGeneration == 0 || sidecarSet.Generation > sidecarSet.Status.ObservedGeneration {
+		return "Waiting for SidecarSet spec update to be observed...\n", false, nil
+	}
+
+	status := sidecarSet.Status
+	desiredUpdated := status.MatchedPods
+	if partition := sidecarSet.Spec.UpdateStrategy.Partition; partition != nil {
+		p, err := intstr.GetValueFromIntOrPercent(partition, int(status.MatchedPods), true)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid partition of SidecarSet %q: %v", sidecarSet.Name, err)
+		}
+		desiredUpdated -= int32(p)
+		if desiredUpdated < 0 {
+			desiredUpdated = 0
+		}
+	}
+
+	if status.UpdatedPods < desiredUpdated {
+		return fmt.Sprintf("Waiting for SidecarSet %q rollout to finish: %d out of %d matched pods have been updated, %d are ready...\n",
+			sidecarSet.Name, status.UpdatedPods, desiredUpdated, status.ReadyPods), false, nil
+	}
+	if status.UpdatedReadyPods < desiredUpdated {
+		return fmt.Sprintf("Waiting for SidecarSet %q rollout to finish: %d of %d updated pods are ready...\n",
+			sidecarSet.Name, status.UpdatedReadyPods, desiredUpdated), false, nil
+	}
+	return fmt.Sprintf("SidecarSet %q rolling update complete: %d of %d matched pods updated, %d ready\n",
+		sidecarSet.Name, status.UpdatedPods, status.MatchedPods, status.ReadyPods), true, nil
+}
+
+// Status returns a message describing broadcastjob status, and a bool value indicating if the status is considered done.
+func (s *BroadcastJobStatusViewer) Status(obj runtime.Unstructured, revision int64) (string, bool, error) {
+	job := &kruiseappsv1alpha1.BroadcastJob{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), job)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, job, err)
+	}
+
+	status := job.Status
+	switch status.Phase {
+	case kruiseappsv1alpha1.PhaseCompleted:
+		return fmt.Sprintf("BroadcastJob %q completed: %d succeeded, %d failed of %d desired\n",
+			job.Name, status.Succeeded, status.Failed, status.Desired), true, nil
+	case kruiseappsv1alpha1.PhaseFailed:
+		return "", true, fmt.Errorf("BroadcastJob %q failed: %d succeeded, %d failed of %d desired",
+			job.Name, status.Succeeded, status.Failed, status.Desired)
+	case kruiseappsv1alpha1.PhasePaused:
+		return fmt.Sprintf("BroadcastJob %q is paused: %d active, %d succeeded, %d failed of %d desired...\n",
+			job.Name, status.Active, status.Succeeded, status.Failed, status.Desired), false, nil
+	}
+	return fmt.Sprintf("Waiting for BroadcastJob %q to finish: %d active, %d succeeded, %d failed of %d desired...\n",
+		job.Name, status.Active, status.Succeeded, status.Failed, status.Desired), false, nil
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func toUnstructured(t *testing.T, obj runtime.Object) runtime.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: content}
+}
+
+func TestAdvancedDaemonSetStatusViewerStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy kruiseappsv1alpha1.RollingUpdateDaemonSet
+		status   kruiseappsv1alpha1.DaemonSetStatus
+		// selectedNodes is the number of nodes selected by rollingUpdate.selector, unknown if nil
+		selectedNodes *int32
+		done          bool
+	}{
+		{
+			name:   "waiting for updated pods",
+			status: kruiseappsv1alpha1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 2, NumberAvailable: 4},
+			done:   false,
+		},
+		{
+			name:     "partitioned roll out complete",
+			strategy: kruiseappsv1alpha1.RollingUpdateDaemonSet{Partition: int32Ptr(2)},
+			status:   kruiseappsv1alpha1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 2, NumberAvailable: 4},
+			done:     true,
+		},
+		{
+			name:     "node selector waits for available pods",
+			strategy: kruiseappsv1alpha1.RollingUpdateDaemonSet{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}}},
+			status:   kruiseappsv1alpha1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 1, NumberAvailable: 3},
+			done:     false,
+		},
+		{
+			name:     "node selector roll out complete",
+			strategy: kruiseappsv1alpha1.RollingUpdateDaemonSet{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}}},
+			status:   kruiseappsv1alpha1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 1, NumberAvailable: 4},
+			done:     true,
+		},
+		{
+			name:     "node selector waits for any updated pod",
+			strategy: kruiseappsv1alpha1.RollingUpdateDaemonSet{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}}},
+			status:   kruiseappsv1alpha1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 0, NumberAvailable: 4},
+			done:     false,
+		},
+		{
+			name:          "node selector waits for pods on selected nodes",
+			strategy:      kruiseappsv1alpha1.RollingUpdateDaemonSet{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}}},
+			status:        kruiseappsv1alpha1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 1, NumberAvailable: 4},
+			selectedNodes: int32Ptr(2),
+			done:          false,
+		},
+		{
+			name:          "node selector roll out complete on selected nodes",
+			strategy:      kruiseappsv1alpha1.RollingUpdateDaemonSet{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}}},
+			status:        kruiseappsv1alpha1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 2, NumberAvailable: 4},
+			selectedNodes: int32Ptr(2),
+			done:          true,
+		},
+		{
+			name:          "node selector with partition roll out complete",
+			strategy:      kruiseappsv1alpha1.RollingUpdateDaemonSet{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}}, Partition: int32Ptr(1)},
+			status:        kruiseappsv1alpha1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 1, NumberAvailable: 4},
+			selectedNodes: int32Ptr(2),
+			done:          true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ds := &kruiseappsv1alpha1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Generation: 1},
+				Spec: kruiseappsv1alpha1.DaemonSetSpec{UpdateStrategy: kruiseappsv1alpha1.DaemonSetUpdateStrategy{
+					Type:          kruiseappsv1alpha1.RollingUpdateDaemonSetStrategyType,
+					RollingUpdate: &test.strategy,
+				}},
+				Status: test.status,
+			}
+			ds.Status.ObservedGeneration = 1
+
+			viewer := &AdvancedDaemonSetStatusViewer{}
+			if test.selectedNodes != nil {
+				viewer.CountSelectedNodes = func(*kruiseappsv1alpha1.DaemonSet, labels.Selector) (int32, error) {
+					return *test.selectedNodes, nil
+				}
+			}
+			msg, done, err := viewer.Status(toUnstructured(t, ds), 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if done != test.done {
+				t.Errorf("expected done %v, got %v with message %q", test.done, done, msg)
+			}
+		})
+	}
+}
+
+func TestAdvancedDaemonSetStatusViewerObservedGeneration(t *testing.T) {
+	ds := &kruiseappsv1alpha1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Generation: 2},
+		Spec: kruiseappsv1alpha1.DaemonSetSpec{UpdateStrategy: kruiseappsv1alpha1.DaemonSetUpdateStrategy{
+			Type: kruiseappsv1alpha1.RollingUpdateDaemonSetStrategyType,
+			RollingUpdate: &kruiseappsv1alpha1.RollingUpdateDaemonSet{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}},
+			},
+		}},
+		Status: kruiseappsv1alpha1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 4, NumberAvailable: 4},
+	}
+	for _, observed := range []int64{0, 1} {
+		ds.Status.ObservedGeneration = observed
+		if msg, done, err := (&AdvancedDaemonSetStatusViewer{}).Status(toUnstructured(t, ds), 0); err != nil || done {
+			t.Errorf("expected not done with observed generation %d, got %v with message %q and error %v", observed, done, msg, err)
+		}
+	}
+}
+
+func TestCloneSetStatusViewerStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  kruiseappsv1alpha1.CloneSetUpdateStrategyType
+		partition *intstr.IntOrString
+		status    kruiseappsv1alpha1.CloneSetStatus
+		done      bool
+	}{
+		{
+			name:     "waiting for updated pods",
+			strategy: kruiseappsv1alpha1.RecreateCloneSetUpdateStrategyType,
+			status:   kruiseappsv1alpha1.CloneSetStatus{Replicas: 10, ReadyReplicas: 10, UpdatedReplicas: 6, UpdatedReadyReplicas: 6},
+		},
+		{
+			name:      "recreate partitioned roll out complete",
+			strategy:  kruiseappsv1alpha1.RecreateCloneSetUpdateStrategyType,
+			partition: intstrPtr(intstr.FromInt(4)),
+			status:    kruiseappsv1alpha1.CloneSetStatus{Replicas: 10, ReadyReplicas: 10, UpdatedReplicas: 6, UpdatedReadyReplicas: 6},
+			done:      true,
+		},
+		{
+			name:      "percentage partition rounded up",
+			strategy:  kruiseappsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType,
+			partition: intstrPtr(intstr.FromString("35%")),
+			status:    kruiseappsv1alpha1.CloneSetStatus{Replicas: 10, ReadyReplicas: 10, UpdatedReplicas: 6, UpdatedReadyReplicas: 6},
+			done:      true,
+		},
+		{
+			name:      "waiting for updated pods ready",
+			strategy:  kruiseappsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType,
+			partition: intstrPtr(intstr.FromString("40%")),
+			status:    kruiseappsv1alpha1.CloneSetStatus{Replicas: 10, ReadyReplicas: 10, UpdatedReplicas: 6, UpdatedReadyReplicas: 5},
+		},
+		{
+			name:     "roll out complete",
+			strategy: kruiseappsv1alpha1.RecreateCloneSetUpdateStrategyType,
+			status:   kruiseappsv1alpha1.CloneSetStatus{Replicas: 10, ReadyReplicas: 10, UpdatedReplicas: 10, UpdatedReadyReplicas: 10},
+			done:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cs := &kruiseappsv1alpha1.CloneSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Generation: 1},
+				Spec: kruiseappsv1alpha1.CloneSetSpec{
+					Replicas:       int32Ptr(10),
+					UpdateStrategy: kruiseappsv1alpha1.CloneSetUpdateStrategy{Type: test.strategy, Partition: test.partition},
+				},
+				Status: test.status,
+			}
+			cs.Status.ObservedGeneration = 1
+
+			msg, done, err := (&CloneSetStatusViewer{}).Status(toUnstructured(t, cs), 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if done != test.done {
+				t.Errorf("expected done %v, got %v with message %q", test.done, done, msg)
+			}
+		})
+	}
+}
+
+func TestUnitedDeploymentStatusViewerStatus(t *testing.T) {
+	ud := &kruiseappsv1alpha1.UnitedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Generation: 1},
+		Spec:       kruiseappsv1alpha1.UnitedDeploymentSpec{Replicas: int32Ptr(6)},
+		Status: kruiseappsv1alpha1.UnitedDeploymentStatus{
+			ObservedGeneration:   1,
+			Replicas:             6,
+			ReadyReplicas:        6,
+			UpdatedReplicas:      4,
+			UpdatedReadyReplicas: 4,
+			SubsetReplicas:       map[string]int32{"subset-a": 3, "subset-b": 3},
+			UpdateStatus:         &kruiseappsv1alpha1.UpdateStatus{CurrentPartitions: map[string]int32{"subset-a": 1, "subset-b": 1}},
+		},
+	}
+
+	if _, done, err := (&UnitedDeploymentStatusViewer{}).Status(toUnstructured(t, ud), 0); err != nil || !done {
+		t.Fatalf("expected partitioned roll out complete, got done %v, err %v", done, err)
+	}
+
+	ud.Status.UpdateStatus.CurrentPartitions = nil
+	if _, done, err := (&UnitedDeploymentStatusViewer{}).Status(toUnstructured(t, ud), 0); err != nil || done {
+		t.Fatalf("expected waiting for updated pods, got done %v, err %v", done, err)
+	}
+}
+
+func TestSidecarSetStatusViewerStatus(t *testing.T) {
+	partition := intstr.FromString("50%")
+	sidecarSet := &kruiseappsv1alpha1.SidecarSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Generation: 1},
+		Spec:       kruiseappsv1alpha1.SidecarSetSpec{UpdateStrategy: kruiseappsv1alpha1.SidecarSetUpdateStrategy{Partition: &partition}},
+		Status:     kruiseappsv1alpha1.SidecarSetStatus{ObservedGeneration: 1, MatchedPods: 4, UpdatedPods: 2, ReadyPods: 4, UpdatedReadyPods: 2},
+	}
+
+	if _, done, err := (&SidecarSetStatusViewer{}).Status(toUnstructured(t, sidecarSet), 0); err != nil || !done {
+		t.Fatalf("expected partitioned roll out complete, got done %v, err %v", done, err)
+	}
+
+	sidecarSet.Spec.UpdateStrategy.Partition = nil
+	if _, done, err := (&SidecarSetStatusViewer{}).Status(toUnstructured(t, sidecarSet), 0); err != nil || done {
+		t.Fatalf("expected waiting for updated pods, got done %v, err %v", done, err)
+	}
+}
+
+func TestBroadcastJobStatusViewerStatus(t *testing.T) {
+	tests := []struct {
+		phase   kruiseappsv1alpha1.BroadcastJobPhase
+		done    bool
+		wantErr bool
+	}{
+		{phase: kruiseappsv1alpha1.PhaseRunning},
+		{phase: kruiseappsv1alpha1.PhasePaused},
+		{phase: kruiseappsv1alpha1.PhaseCompleted, done: true},
+		{phase: kruiseappsv1alpha1.PhaseFailed, done: true, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.phase), func(t *testing.T) {
+			job := &kruiseappsv1alpha1.BroadcastJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Status:     kruiseappsv1alpha1.BroadcastJobStatus{Phase: test.phase, Desired: 3, Succeeded: 1, Active: 2},
+			}
+			_, done, err := (&BroadcastJobStatusViewer{}).Status(toUnstructured(t, job), 0)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+			if done != test.done {
+				t.Errorf("expected done %v, got %v", test.done, done)
+			}
+		})
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func intstrPtr(i intstr.IntOrString) *intstr.IntOrString {
+	return &i
+}
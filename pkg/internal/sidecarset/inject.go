@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Matches returns whether the SidecarSet injects into the pods with the given namespace and labels.
+// A SidecarSet without a selector matches no pods.
+func Matches(sidecarSet *kruiseappsv1alpha1.SidecarSet, namespace string, podLabels map[string]string) (bool, error) {
+	if len(sidecarSet.Spec.Namespace) > 0 && sidecarSet.Spec.Namespace != namespace {
+		return false, nil
+	}
+	if sidecarSet.Spec.Selector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(sidecarSet.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(podLabels)), nil
+}
+
+// Inject returns a copy of the pod template with the sidecars of the SidecarSet injected, the way
+// the SidecarSet webhook does on pod creation:
+//
+// - init containers are appended, and containers are put before or after the app containers by their PodInjectPolicy;
+// - containers or volumes with the same names as existing ones in the pod are not injected;
+// - containers with ShareVolumePolicy enabled mount the volumes of the app containers;
+// - containers get the env vars given by TransferEnv from the app containers.
+//
+// The sidecarset hash annotations are not added, and the UpgradeStrategy of the containers, e.g. hot upgrade, is not taken into account.
+func Inject(sidecarSet *kruiseappsv1alpha1.SidecarSet, template *corev1.PodTemplateSpec) *corev1.PodTemplateSpec {
+	return InjectInto(sidecarSet, template, template.Spec.Containers)
+}
+
+// InjectInto is like Inject, with the app containers given apart from the containers of the template.
+// It injects several SidecarSets one after another without taking the sidecars injected before for app containers.
+func InjectInto(sidecarSet *kruiseappsv1alpha1.SidecarSet, template *corev1.PodTemplateSpec, appContainers []corev1.Container) *corev1.PodTemplateSpec {
+	injected := template.DeepCopy()
+	spec := &injected.Spec
+
+	for _, sidecar := range sidecarSet.Spec.InitContainers {
+		if !hasContainer(spec.InitContainers, sidecar.Name) {
+			spec.InitContainers = append(spec.InitContainers, sidecar.Container)
+		}
+	}
+
+	var before, after []corev1.Container
+	for _, sidecar := range sidecarSet.Spec.Containers {
+		if hasContainer(spec.Containers, sidecar.Name) {
+			continue
+		}
+		container := *sidecar.Container.DeepCopy()
+		if sidecar.ShareVolumePolicy.Type == kruiseappsv1alpha1.ShareVolumePolicyEnabled {
+			container.VolumeMounts = appendVolumeMounts(container.VolumeMounts, appContainers)
+		}
+		container.Env = appendTransferEnv(container.Env, sidecar.TransferEnv, appContainers)
+		if sidecar.PodInjectPolicy == kruiseappsv1alpha1.BeforeAppContainerType {
+			before = append(before, container)
+		} else {
+			after = append(after, container)
+		}
+	}
+	containers := make([]corev1.Container, 0, len(before)+len(spec.Containers)+len(after))
+	containers = append(containers, before...)
+	containers = append(containers, spec.Containers...)
+	spec.Containers = append(containers, after...)
+
+	for _, volume := range sidecarSet.Spec.Volumes {
+		if !hasVolume(spec.Volumes, volume.Name) {
+			spec.Volumes = append(spec.Volumes, volume)
+		}
+	}
+	return injected
+}
+
+// appendVolumeMounts appends the volume mounts of the app containers which conflict with none of mounts by name or path.
+func appendVolumeMounts(mounts []corev1.VolumeMount, appContainers []corev1.Container) []corev1.VolumeMount {
+	for _, c := range appContainers {
+		for _, mount := range c.VolumeMounts {
+			conflicted := false
+			for _, existing := range mounts {
+				if existing.Name == mount.Name || existing.MountPath == mount.MountPath {
+					conflicted = true
+					break
+				}
+			}
+			if !conflicted {
+				mounts = append(mounts, mount)
+			}
+		}
+	}
+	return mounts
+}
+
+// appendTransferEnv appends the env vars given by transferEnv found in the app containers.
+func appendTransferEnv(env []corev1.EnvVar, transferEnv []kruiseappsv1alpha1.TransferEnvVar, appContainers []corev1.Container) []corev1.EnvVar {
+	for _, transfer := range transferEnv {
+		for _, c := range appContainers {
+			if c.Name != transfer.SourceContainerName {
+				continue
+			}
+			for _, e := range c.Env {
+				if e.Name == transfer.EnvName {
+					env = append(env, e)
+				}
+			}
+		}
+	}
+	return env
+}
+
+func hasContainer(containers []corev1.Container, name string) bool {
+	for _, c := range containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolume(volumes []corev1.Volume, name string) bool {
+	for _, v := range volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecarset
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatches(t *testing.T) {
+	sidecarSet := &kruiseappsv1alpha1.SidecarSet{}
+	matched, err := Matches(sidecarSet, "default", map[string]string{"app": "web"})
+	assert.NoError(t, err)
+	assert.False(t, matched)
+
+	sidecarSet.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	matched, err = Matches(sidecarSet, "default", map[string]string{"app": "web", "zone": "a"})
+	assert.NoError(t, err)
+	assert.True(t, matched)
+
+	matched, err = Matches(sidecarSet, "default", map[string]string{"app": "db"})
+	assert.NoError(t, err)
+	assert.False(t, matched)
+
+	sidecarSet.Spec.Namespace = "prod"
+	matched, err = Matches(sidecarSet, "default", map[string]string{"app": "web"})
+	assert.NoError(t, err)
+	assert.False(t, matched)
+}
+
+func TestInject(t *testing.T) {
+	sidecarSet := &kruiseappsv1alpha1.SidecarSet{
+		Spec: kruiseappsv1alpha1.SidecarSetSpec{
+			InitContainers: []kruiseappsv1alpha1.SidecarContainer{
+				{Container: corev1.Container{Name: "init-proxy"}},
+			},
+			Containers: []kruiseappsv1alpha1.SidecarContainer{
+				{
+					Container:       corev1.Container{Name: "proxy", VolumeMounts: []corev1.VolumeMount{{Name: "proxy-conf", MountPath: "/etc/proxy"}}},
+					PodInjectPolicy: kruiseappsv1alpha1.BeforeAppContainerType,
+					TransferEnv:     []kruiseappsv1alpha1.TransferEnvVar{{SourceContainerName: "app", EnvName: "ZONE"}},
+				},
+				{
+					Container:         corev1.Container{Name: "logger"},
+					ShareVolumePolicy: kruiseappsv1alpha1.ShareVolumePolicy{Type: kruiseappsv1alpha1.ShareVolumePolicyEnabled},
+				},
+				{Container: corev1.Container{Name: "app"}},
+			},
+			Volumes: []corev1.Volume{{Name: "proxy-conf"}, {Name: "logs"}},
+		},
+	}
+	template := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:         "app",
+					Env:          []corev1.EnvVar{{Name: "ZONE", Value: "a"}, {Name: "DEBUG", Value: "1"}},
+					VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/var/log/app"}},
+				},
+			},
+			Volumes: []corev1.Volume{{Name: "logs", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+		},
+	}
+
+	injected := Inject(sidecarSet, template)
+	assert.Equal(t, []corev1.Container{{Name: "init-proxy"}}, injected.Spec.InitContainers)
+	assert.Equal(t, []corev1.Container{
+		{
+			Name:         "proxy",
+			Env:          []corev1.EnvVar{{Name: "ZONE", Value: "a"}},
+			VolumeMounts: []corev1.VolumeMount{{Name: "proxy-conf", MountPath: "/etc/proxy"}},
+		},
+		template.Spec.Containers[0],
+		{
+			Name:         "logger",
+			VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/var/log/app"}},
+		},
+	}, injected.Spec.Containers)
+	assert.Equal(t, []corev1.Volume{template.Spec.Volumes[0], {Name: "proxy-conf"}}, injected.Spec.Volumes)
+
+	// the template itself is left untouched
+	assert.Len(t, template.Spec.Containers, 1)
+}
+
+func TestInjectInto(t *testing.T) {
+	mesh := &kruiseappsv1alpha1.SidecarSet{
+		Spec: kruiseappsv1alpha1.SidecarSetSpec{
+			Containers: []kruiseappsv1alpha1.SidecarContainer{
+				{Container: corev1.Container{Name: "proxy", VolumeMounts: []corev1.VolumeMount{{Name: "proxy-conf", MountPath: "/etc/proxy"}}}},
+			},
+		},
+	}
+	logging := &kruiseappsv1alpha1.SidecarSet{
+		Spec: kruiseappsv1alpha1.SidecarSetSpec{
+			Containers: []kruiseappsv1alpha1.SidecarContainer{
+				{
+					Container:         corev1.Container{Name: "logger"},
+					ShareVolumePolicy: kruiseappsv1alpha1.ShareVolumePolicy{Type: kruiseappsv1alpha1.ShareVolumePolicyEnabled},
+				},
+				{Container: corev1.Container{Name: "proxy", Image: "other-proxy"}},
+			},
+		},
+	}
+	template := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/var/log/app"}}},
+			},
+		},
+	}
+
+	// the logger shares the volumes of the app only, not those of the proxy injected before,
+	// and the proxy of the second sidecarset is not injected again
+	injected := InjectInto(mesh, template, template.Spec.Containers)
+	injected = InjectInto(logging, injected, template.Spec.Containers)
+	assert.Equal(t, []corev1.Container{
+		template.Spec.Containers[0],
+		mesh.Spec.Containers[0].Container,
+		{Name: "logger", VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/var/log/app"}}},
+	}, injected.Spec.Containers)
+}
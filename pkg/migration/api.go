@@ -18,12 +18,14 @@ package migration
 
 import (
 	"github.com/openkruise/kruise-tools/pkg/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
 type Control interface {
 	Submit(src api.ResourceRef, dst api.ResourceRef, opts Options) (Result, error)
 	Query(ID types.UID) (Result, error)
+	Report(ID types.UID) (Report, error)
 }
 
 type Options struct {
@@ -37,6 +39,12 @@ type Options struct {
 	// TimeoutSeconds indicates the timeout seconds that migration exceeded.
 	// Defaults to no limited.
 	TimeoutSeconds *int32
+	// LockHolder identifies who is running the migration, it is recorded in the lock on workloads.
+	// Defaults to user@hostname of the current process.
+	LockHolder string
+	// LockDurationSeconds indicates how long the lock on workloads stays valid without renewing.
+	// Defaults to 60.
+	LockDurationSeconds *int32
 }
 
 type Result struct {
@@ -55,3 +63,50 @@ const (
 	MigrateSucceeded MigrateState = "Succeeded"
 	MigrateFailed    MigrateState = "Failed"
 )
+
+// Report is the auditable record of a migration task.
+type Report struct {
+	ID      types.UID    `json:"id"`
+	State   MigrateState `json:"state"`
+	Message string       `json:"message,omitempty"`
+
+	Src api.ResourceRef `json:"src"`
+	Dst api.ResourceRef `json:"dst"`
+
+	StartTime  metav1.Time  `json:"startTime"`
+	FinishTime *metav1.Time `json:"finishTime,omitempty"`
+
+	SrcMigratedReplicas int32 `json:"srcMigratedReplicas"`
+	DstMigratedReplicas int32 `json:"dstMigratedReplicas"`
+	// SrcReplicas and DstReplicas are the replicas of workloads when the task finished.
+	SrcReplicas *int32 `json:"srcReplicas,omitempty"`
+	DstReplicas *int32 `json:"dstReplicas,omitempty"`
+
+	Steps []Step `json:"steps"`
+}
+
+// Step is a single scaling of src or dst workload during migration.
+type Step struct {
+	Workload api.ResourceRef `json:"workload"`
+	Action   StepAction      `json:"action"`
+
+	FromReplicas int32 `json:"fromReplicas"`
+	ToReplicas   int32 `json:"toReplicas"`
+
+	// StartTime is the time when the workload was scaled.
+	StartTime metav1.Time `json:"startTime"`
+	// FinishTime is the time when the next step started or the task finished.
+	FinishTime *metav1.Time `json:"finishTime,omitempty"`
+	// WaitingDuration is how long it waited for workloads after scaling.
+	WaitingDuration *metav1.Duration `json:"waitingDuration,omitempty"`
+
+	CreatedPods []string `json:"createdPods,omitempty"`
+	DeletedPods []string `json:"deletedPods,omitempty"`
+}
+
+type StepAction string
+
+const (
+	StepScaleOut StepAction = "ScaleOut"
+	StepScaleIn  StepAction = "ScaleIn"
+)
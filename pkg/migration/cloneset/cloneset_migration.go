@@ -27,13 +27,18 @@ import (
 	"github.com/openkruise/kruise-tools/pkg/migration"
 	"github.com/openkruise/kruise-tools/pkg/utils"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -43,6 +48,10 @@ import (
 var (
 	// TODO: make it as an option
 	maxConcurrentReconciles = 5
+
+	// srcPodsDeletionTimeout is how long a task waits for the src pods to be deleted after all replicas migrated,
+	// as some may never finish terminating, e.g. because of finalizers or an unavailable node.
+	srcPodsDeletionTimeout = 5 * time.Minute
 )
 
 type control struct {
@@ -65,11 +74,23 @@ type task struct {
 	dst  api.ResourceRef
 	opts migration.Options
 
+	srcUID types.UID
+	dstUID types.UID
+
 	srcUpdatedGeneration int64
 	dstUpdatedGeneration int64
 
+	// lock is written onto both src and dst to prevent migrations from other processes
+	lock         *migration.Lock
+	lockDuration time.Duration
+
+	pendingStep *pendingStep
+	// migratedTime is when all replicas were found migrated
+	migratedTime time.Time
+
 	mu     sync.Mutex
 	result migration.Result
+	report migration.Report
 }
 
 var _ migration.Control = &control{}
@@ -114,8 +135,8 @@ func NewControl(cfg *rest.Config, stopChan <-chan struct{}) (migration.Control,
 func (c *control) Submit(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (migration.Result, error) {
 	if opts.Replicas != nil && *opts.Replicas <= 0 {
 		return migration.Result{}, fmt.Errorf("invlid replicas %v", *opts.Replicas)
-	} else if src.GetGroupVersionKind() != api.DeploymentKind {
-		return migration.Result{}, fmt.Errorf("invalid src type, currently only support %v", api.DeploymentKind.String())
+	} else if _, err := newSrcObject(src.GetGroupVersionKind()); err != nil {
+		return migration.Result{}, err
 	} else if dst.GetGroupVersionKind() != api.CloneSetKind {
 		return migration.Result{}, fmt.Errorf("invalid dst type, must be %v", api.CloneSetKind.String())
 	}
@@ -123,13 +144,17 @@ func (c *control) Submit(src api.ResourceRef, dst api.ResourceRef, opts migratio
 	srcGVK := src.GetGroupVersionKind()
 	dstGVK := dst.GetGroupVersionKind()
 
-	srcDeployment, dstCloneSet, err := getDeploymentAndCloneSetObjects(c.client, &src, &dst)
+	srcObject, dstCloneSet, err := getSrcAndCloneSetObjects(c.client, &src, &dst)
 	if err != nil {
 		return migration.Result{}, err
 	}
+	// the owner would scale the src back while it is scaled in by the migration, e.g. a ReplicaSet of a Deployment
+	if owner := metav1.GetControllerOf(srcObject); owner != nil {
+		return migration.Result{}, fmt.Errorf("%v is controlled by %s %s, migrate the owner instead", src, owner.Kind, owner.Name)
+	}
 
 	if opts.Replicas == nil {
-		opts.Replicas = srcDeployment.Spec.Replicas
+		opts.Replicas = getSrcReplicas(srcObject)
 	}
 	if opts.MaxSurge == nil {
 		opts.MaxSurge = func() *int32 { var i int32 = 1; return &i }()
@@ -137,6 +162,15 @@ func (c *control) Submit(src api.ResourceRef, dst api.ResourceRef, opts migratio
 	if *opts.MaxSurge <= 0 {
 		return migration.Result{}, fmt.Errorf("maxSurge must be integar more than zore")
 	}
+	if len(opts.LockHolder) == 0 {
+		opts.LockHolder = migration.DefaultLockHolder()
+	}
+	if opts.LockDurationSeconds == nil {
+		opts.LockDurationSeconds = func() *int32 { i := migration.DefaultLockDurationSeconds; return &i }()
+	}
+	if *opts.LockDurationSeconds <= 0 {
+		return migration.Result{}, fmt.Errorf("lockDurationSeconds must be integar more than zore")
+	}
 
 	c.Lock()
 	defer c.Unlock()
@@ -154,6 +188,12 @@ func (c *control) Submit(src api.ResourceRef, dst api.ResourceRef, opts migratio
 	}
 
 	id := uuid.NewUUID()
+	lockDuration := time.Duration(*opts.LockDurationSeconds) * time.Second
+	lock := migration.NewLock(opts.LockHolder, id, lockDuration)
+	if err := c.acquireLocks(lock, srcObject, dstCloneSet); err != nil {
+		return migration.Result{}, err
+	}
+
 	t := task{
 		ID:                id,
 		creationTimestamp: metav1.Now(),
@@ -162,11 +202,24 @@ func (c *control) Submit(src api.ResourceRef, dst api.ResourceRef, opts migratio
 		dst:  dst,
 		opts: opts,
 
-		srcUpdatedGeneration: srcDeployment.Generation,
+		srcUID: srcObject.GetUID(),
+		dstUID: dstCloneSet.UID,
+
+		srcUpdatedGeneration: srcObject.GetGeneration(),
 		dstUpdatedGeneration: dstCloneSet.Generation,
 
+		lock:         lock,
+		lockDuration: lockDuration,
+
 		result: migration.Result{ID: id, State: migration.MigrateExecuting},
 	}
+	t.report = migration.Report{
+		ID:        id,
+		State:     migration.MigrateExecuting,
+		Src:       src,
+		Dst:       dst,
+		StartTime: t.creationTimestamp,
+	}
 	c.tasks[t.ID] = &t
 	c.executingTasks[t.src] = &t
 	c.executingTasks[t.dst] = &t
@@ -194,13 +247,10 @@ func (c *control) addEventHandler(gvk schema.GroupVersionKind) error {
 		if err != nil {
 			return fmt.Errorf("failed to get informer for %v: %v", gvk, err)
 		}
-		if gvk == api.DeploymentKind {
-			informer.AddEventHandler(&deploymentHandler{ctrl: c})
-		} else if gvk == api.CloneSetKind {
-			informer.AddEventHandler(&cloneSetHandler{ctrl: c})
-		} else {
+		if _, err := newSrcObject(gvk); err != nil && gvk != api.CloneSetKind {
 			return fmt.Errorf("unsupported gvk %v", gvk)
 		}
+		informer.AddEventHandler(&workloadHandler{ctrl: c, gvk: gvk})
 		c.handledGVKs[gvk] = struct{}{}
 	}
 	return nil
@@ -231,32 +281,60 @@ func (c *control) processNextWorkItem() bool {
 	return true
 }
 
-func (c *control) reconcile(ID types.UID) error {
+func (c *control) reconcile(ID types.UID) (err error) {
 	task := c.getTask(ID)
 	if task.result.State != migration.MigrateExecuting {
 		return nil
-	} else if task.result.DstMigratedReplicas == *task.opts.Replicas && task.result.SrcMigratedReplicas == *task.opts.Replicas {
-		c.finishTask(task, migration.MigrateSucceeded, "")
-		return nil
 	} else if task.opts.TimeoutSeconds != nil && time.Since(task.creationTimestamp.Time) > time.Duration(*task.opts.TimeoutSeconds)*time.Second {
-		c.finishTask(task, migration.MigrateFailed, fmt.Sprintf("task timeout exceeded"))
+		// the replicas already migrated only wait for the src pods to be deleted, which is not a failure
+		if allReplicasMigrated(task) {
+			c.finishTask(task, migration.MigrateSucceeded, "")
+		} else {
+			c.finishTask(task, migration.MigrateFailed, fmt.Sprintf("task timeout exceeded"))
+		}
 		return nil
 	}
 
-	srcDeployment, dstCloneSet, err := getDeploymentAndCloneSetObjects(c.cache, &task.src, &task.dst)
+	srcObject, dstCloneSet, err := getSrcAndCloneSetObjects(c.cache, &task.src, &task.dst)
 	if err != nil {
 		c.finishTask(task, migration.MigrateFailed, err.Error())
 		return nil
 	}
 
-	if srcDeployment.Generation < task.srcUpdatedGeneration || dstCloneSet.Generation < task.dstUpdatedGeneration {
+	if srcObject.GetGeneration() < task.srcUpdatedGeneration || dstCloneSet.Generation < task.dstUpdatedGeneration {
 		// cache has not synced
 		return nil
-	} else if srcDeployment.Generation != srcDeployment.Status.ObservedGeneration || dstCloneSet.Generation != dstCloneSet.Status.ObservedGeneration {
+	}
+
+	for _, obj := range []metav1.Object{srcObject, dstCloneSet} {
+		if err := migration.CheckLock(obj, task.ID); err != nil {
+			c.finishTask(task, migration.MigrateFailed, fmt.Sprintf("lost migration lock: %v", err))
+			return nil
+		}
+	}
+
+	// keep renewing the lock while waiting for workloads, errors are retried by the rate limited queue instead
+	defer func() {
+		if err == nil {
+			c.queue.AddAfter(ID, task.lockDuration/3)
+		}
+	}()
+	if time.Since(task.lock.RenewTime.Time) > task.lockDuration/3 {
+		return c.renewLocks(task, srcObject, dstCloneSet)
+	}
+
+	if srcObject.GetGeneration() != getSrcObservedGeneration(srcObject) || dstCloneSet.Generation != dstCloneSet.Status.ObservedGeneration {
 		// workload controller has not reconciled
 		return nil
 	}
 
+	if allReplicasMigrated(task) {
+		if done, message := srcPodsDeleted(task, srcObject, time.Now()); done {
+			c.finishTask(task, migration.MigrateSucceeded, message)
+		}
+		return nil
+	}
+
 	// dst need scale out
 	if task.result.DstMigratedReplicas < *task.opts.Replicas {
 		deltaSurge := *task.opts.MaxSurge - (task.result.DstMigratedReplicas - task.result.SrcMigratedReplicas)
@@ -264,10 +342,19 @@ func (c *control) reconcile(ID types.UID) error {
 		maxScaleOut := utils.Int32Min(deltaSurge, deltaReplicas)
 
 		if maxScaleOut > 0 {
+			selector, err := metav1.LabelSelectorAsSelector(dstCloneSet.Spec.Selector)
+			if err != nil {
+				c.finishTask(task, migration.MigrateFailed, fmt.Sprintf("invalid selector of %v: %v", task.dst, err))
+				return nil
+			}
+			pods := c.stepPods(task, task.dst, selector)
+			fromReplicas := *dstCloneSet.Spec.Replicas
 			*dstCloneSet.Spec.Replicas += maxScaleOut
+			migration.SetLock(dstCloneSet, task.lock)
 			if err := c.client.Update(context.TODO(), dstCloneSet); err != nil {
 				return err
 			}
+			c.beginStep(task, task.dst, task.dstUID, migration.StepScaleOut, fromReplicas, *dstCloneSet.Spec.Replicas, selector, pods)
 			task.dstUpdatedGeneration = dstCloneSet.Generation
 			c.updateTask(task, 0, maxScaleOut)
 			return nil
@@ -278,15 +365,25 @@ func (c *control) reconcile(ID types.UID) error {
 	if task.result.SrcMigratedReplicas < *task.opts.Replicas {
 		deltaReplicas := *task.opts.Replicas - task.result.SrcMigratedReplicas
 		deltaMigrated := task.result.DstMigratedReplicas - task.result.SrcMigratedReplicas
-		maxScaleIn := utils.Int32Min(*srcDeployment.Spec.Replicas, deltaReplicas, deltaMigrated)
+		srcReplicas := getSrcReplicas(srcObject)
+		maxScaleIn := utils.Int32Min(*srcReplicas, deltaReplicas, deltaMigrated)
 
 		// must wait for all pods in CloneSet available
 		if maxScaleIn > 0 && *dstCloneSet.Spec.Replicas == dstCloneSet.Status.AvailableReplicas {
-			*srcDeployment.Spec.Replicas -= maxScaleIn
-			if err := c.client.Update(context.TODO(), srcDeployment); err != nil {
+			selector, err := getSrcSelector(srcObject)
+			if err != nil {
+				c.finishTask(task, migration.MigrateFailed, fmt.Sprintf("invalid selector of %v: %v", task.src, err))
+				return nil
+			}
+			pods := c.stepPods(task, task.src, selector)
+			fromReplicas := *srcReplicas
+			setSrcReplicas(srcObject, *srcReplicas-maxScaleIn)
+			migration.SetLock(srcObject, task.lock)
+			if err := c.client.Update(context.TODO(), srcObject); err != nil {
 				return err
 			}
-			task.srcUpdatedGeneration = srcDeployment.Generation
+			c.beginStep(task, task.src, task.srcUID, migration.StepScaleIn, fromReplicas, fromReplicas-maxScaleIn, selector, pods)
+			task.srcUpdatedGeneration = srcObject.GetGeneration()
 			c.updateTask(task, maxScaleIn, 0)
 			return nil
 		}
@@ -295,6 +392,27 @@ func (c *control) reconcile(ID types.UID) error {
 	return nil
 }
 
+// allReplicasMigrated returns whether both the dst and the src have been scaled by all the replicas to migrate.
+func allReplicasMigrated(t *task) bool {
+	return t.result.DstMigratedReplicas == *t.opts.Replicas && t.result.SrcMigratedReplicas == *t.opts.Replicas
+}
+
+// srcPodsDeleted returns whether the task of which all replicas migrated can finish, which waits for the src pods
+// to be deleted to complete the report, but at most srcPodsDeletionTimeout. The message tells the pods not deleted.
+func srcPodsDeleted(t *task, srcObject lockedObject, now time.Time) (bool, string) {
+	if t.migratedTime.IsZero() {
+		t.migratedTime = now
+	}
+	terminating := getSrcStatusReplicas(srcObject) - *getSrcReplicas(srcObject)
+	if terminating <= 0 {
+		return true, ""
+	}
+	if now.Sub(t.migratedTime) < srcPodsDeletionTimeout {
+		return false, ""
+	}
+	return true, fmt.Sprintf("%d src pods are still terminating", terminating)
+}
+
 func (c *control) getTask(ID types.UID) *task {
 	c.RLock()
 	defer c.RUnlock()
@@ -309,6 +427,10 @@ func (c *control) updateTask(t *task, srcMigratedReplicas, dstMigratedReplicas i
 }
 
 func (c *control) finishTask(t *task, state migration.MigrateState, message string) {
+	// report and locks must be completed before the result is visible
+	c.completeReport(t, state, message)
+	c.releaseLocks(t)
+
 	func() {
 		t.mu.Lock()
 		defer t.mu.Unlock()
@@ -322,9 +444,79 @@ func (c *control) finishTask(t *task, state migration.MigrateState, message stri
 	delete(c.executingTasks, t.dst)
 }
 
-func getDeploymentAndCloneSetObjects(reader client.Reader, src, dst *api.ResourceRef) (*apps.Deployment, *appsv1alpha1.CloneSet, error) {
-	srcDeployment := apps.Deployment{}
-	if err := reader.Get(context.TODO(), src.GetNamespacedName(), &srcDeployment); err != nil {
+type lockedObject interface {
+	metav1.Object
+	runtime.Object
+}
+
+// acquireLocks writes the lock onto all objects, and rollbacks the locks already written if any of them fails.
+func (c *control) acquireLocks(lock *migration.Lock, objs ...lockedObject) error {
+	for i, obj := range objs {
+		err := migration.CheckLock(obj, lock.TaskID)
+		if err == nil {
+			migration.SetLock(obj, lock)
+			err = c.client.Update(context.TODO(), obj)
+		}
+		if err != nil {
+			for _, locked := range objs[:i] {
+				c.releaseLock(lock.TaskID, locked)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// renewLocks writes a renewed lock onto all objects by merge patches of the lock annotation alone,
+// which don't conflict with the workload controllers updating the objects.
+// The task keeps its lock until all objects are patched, so that a failed renewal is retried on the next reconcile.
+func (c *control) renewLocks(t *task, objs ...lockedObject) error {
+	lock := *t.lock
+	lock.Renew(t.lockDuration)
+	for _, obj := range objs {
+		patch := client.MergeFrom(obj.DeepCopyObject())
+		migration.SetLock(obj, &lock)
+		if err := c.client.Patch(context.TODO(), obj, patch); err != nil {
+			return fmt.Errorf("failed to renew migration lock on %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	t.lock = &lock
+	return nil
+}
+
+func (c *control) releaseLocks(t *task) {
+	if srcObject, err := newSrcObject(t.src.GetGroupVersionKind()); err == nil {
+		srcObject.SetNamespace(t.src.Namespace)
+		srcObject.SetName(t.src.Name)
+		c.releaseLock(t.ID, srcObject)
+	}
+	c.releaseLock(t.ID, &appsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: t.dst.Namespace, Name: t.dst.Name}})
+}
+
+// releaseLock removes the lock from the object if it is still held by the task.
+func (c *control) releaseLock(taskID types.UID, obj lockedObject) {
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if err := c.client.Get(context.TODO(), key, obj); err != nil {
+			return err
+		}
+		if lock, err := migration.GetLock(obj); err != nil || lock == nil || lock.TaskID != taskID {
+			return nil
+		}
+		migration.RemoveLock(obj)
+		return c.client.Update(context.TODO(), obj)
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		utilruntime.HandleError(fmt.Errorf("failed to release migration lock on %v: %v", key, err))
+	}
+}
+
+func getSrcAndCloneSetObjects(reader client.Reader, src, dst *api.ResourceRef) (lockedObject, *appsv1alpha1.CloneSet, error) {
+	srcObject, err := newSrcObject(src.GetGroupVersionKind())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := reader.Get(context.TODO(), src.GetNamespacedName(), srcObject); err != nil {
 		return nil, nil, fmt.Errorf("failed to get %v: %v", src, err)
 	}
 
@@ -333,5 +525,85 @@ func getDeploymentAndCloneSetObjects(reader client.Reader, src, dst *api.Resourc
 		return nil, nil, fmt.Errorf("failed to get %v: %v", dst, err)
 	}
 
-	return &srcDeployment, &dstCloneSet, nil
+	return srcObject, &dstCloneSet, nil
+}
+
+// newSrcObject returns an empty object of the src workload kind which can be migrated to CloneSet.
+func newSrcObject(gvk schema.GroupVersionKind) (lockedObject, error) {
+	switch gvk {
+	case api.DeploymentKind:
+		return &apps.Deployment{}, nil
+	case api.ReplicaSetKind:
+		return &apps.ReplicaSet{}, nil
+	case api.ReplicationControllerKind:
+		return &corev1.ReplicationController{}, nil
+	}
+	return nil, fmt.Errorf("invalid src type, currently only support %v, %v and %v",
+		api.DeploymentKind.String(), api.ReplicaSetKind.String(), api.ReplicationControllerKind.String())
+}
+
+// getSrcReplicas returns a copy of spec.replicas of the src workload, it defaults to 1 if unset.
+func getSrcReplicas(obj lockedObject) *int32 {
+	var replicas *int32
+	switch o := obj.(type) {
+	case *apps.Deployment:
+		replicas = o.Spec.Replicas
+	case *apps.ReplicaSet:
+		replicas = o.Spec.Replicas
+	case *corev1.ReplicationController:
+		replicas = o.Spec.Replicas
+	}
+	i := int32(1)
+	if replicas != nil {
+		i = *replicas
+	}
+	return &i
+}
+
+// setSrcReplicas sets spec.replicas of the src workload.
+func setSrcReplicas(obj lockedObject, replicas int32) {
+	switch o := obj.(type) {
+	case *apps.Deployment:
+		o.Spec.Replicas = &replicas
+	case *apps.ReplicaSet:
+		o.Spec.Replicas = &replicas
+	case *corev1.ReplicationController:
+		o.Spec.Replicas = &replicas
+	}
+}
+
+func getSrcStatusReplicas(obj lockedObject) int32 {
+	switch o := obj.(type) {
+	case *apps.Deployment:
+		return o.Status.Replicas
+	case *apps.ReplicaSet:
+		return o.Status.Replicas
+	case *corev1.ReplicationController:
+		return o.Status.Replicas
+	}
+	return 0
+}
+
+func getSrcSelector(obj lockedObject) (labels.Selector, error) {
+	switch o := obj.(type) {
+	case *apps.Deployment:
+		return metav1.LabelSelectorAsSelector(o.Spec.Selector)
+	case *apps.ReplicaSet:
+		return metav1.LabelSelectorAsSelector(o.Spec.Selector)
+	case *corev1.ReplicationController:
+		return labels.SelectorFromSet(o.Spec.Selector), nil
+	}
+	return nil, fmt.Errorf("unsupported src type %T", obj)
+}
+
+func getSrcObservedGeneration(obj lockedObject) int64 {
+	switch o := obj.(type) {
+	case *apps.Deployment:
+		return o.Status.ObservedGeneration
+	case *apps.ReplicaSet:
+		return o.Status.ObservedGeneration
+	case *corev1.ReplicationController:
+		return o.Status.ObservedGeneration
+	}
+	return 0
 }
@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloneset
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// patchClient fails all the patches with err.
+type patchClient struct {
+	client.Client
+	err error
+}
+
+func (c *patchClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.err
+}
+
+func TestRenewLocks(t *testing.T) {
+	getObjects := func(c *control) (*apps.Deployment, *appsv1alpha1.CloneSet) {
+		deployment := &apps.Deployment{}
+		assert.NoError(t, c.client.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "web"}, deployment))
+		cloneSet := &appsv1alpha1.CloneSet{}
+		assert.NoError(t, c.client.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "web-cs"}, cloneSet))
+		return deployment, cloneSet
+	}
+	newTask := func() (*control, *task, *migration.Lock) {
+		c, task := newTestControl()
+		task.lockDuration = time.Minute
+		task.lock = migration.NewLock("tester", task.ID, task.lockDuration)
+		task.lock.RenewTime.Time = task.lock.RenewTime.Add(-time.Minute)
+		lock := *task.lock
+		return c, task, &lock
+	}
+
+	// the objects read before are stale after they are updated by the workload controllers,
+	// whose changes are kept by the renewal
+	c, task, oldLock := newTask()
+	deployment, cloneSet := getObjects(c)
+	updated := deployment.DeepCopy()
+	updated.Spec.Replicas = int32Ptr(3)
+	assert.NoError(t, c.client.Update(context.TODO(), updated))
+	assert.NoError(t, c.renewLocks(task, deployment, cloneSet))
+	assert.True(t, task.lock.RenewTime.After(oldLock.RenewTime.Time))
+	deployment, cloneSet = getObjects(c)
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+	for _, obj := range []lockedObject{deployment, cloneSet} {
+		lock, err := migration.GetLock(obj)
+		assert.NoError(t, err)
+		assert.Equal(t, task.lock.ExpireTime.Unix(), lock.ExpireTime.Unix())
+	}
+
+	// the renewal is retried on the next reconcile if any patch fails
+	c, task, oldLock = newTask()
+	deployment, cloneSet = getObjects(c)
+	c.client = &patchClient{Client: c.client, err: fmt.Errorf("unavailable")}
+	assert.Error(t, c.renewLocks(task, deployment, cloneSet))
+	assert.Equal(t, oldLock, task.lock)
+}
+
+func TestGetSrcReplicas(t *testing.T) {
+	deployment := &apps.Deployment{}
+	assert.Equal(t, int32(1), *getSrcReplicas(deployment))
+	assert.Nil(t, deployment.Spec.Replicas)
+
+	setSrcReplicas(deployment, 3)
+	replicas := getSrcReplicas(deployment)
+	*replicas = 2
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+}
+
+func TestSrcPodsDeleted(t *testing.T) {
+	_, task := newTestControl()
+	deployment := &apps.Deployment{Spec: apps.DeploymentSpec{Replicas: int32Ptr(0)}}
+	deployment.Status.Replicas = 2
+	now := time.Now()
+
+	done, _ := srcPodsDeleted(task, deployment, now)
+	assert.False(t, done)
+	done, _ = srcPodsDeleted(task, deployment, now.Add(srcPodsDeletionTimeout-time.Second))
+	assert.False(t, done)
+
+	// the pods never finishing terminating don't block the task forever
+	done, message := srcPodsDeleted(task, deployment, now.Add(srcPodsDeletionTimeout))
+	assert.True(t, done)
+	assert.Equal(t, "2 src pods are still terminating", message)
+
+	deployment.Status.Replicas = 0
+	done, message = srcPodsDeleted(task, deployment, now.Add(time.Second))
+	assert.True(t, done)
+	assert.Empty(t, message)
+}
@@ -17,136 +17,50 @@ limitations under the License.
 package cloneset
 
 import (
-	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	"github.com/openkruise/kruise-tools/pkg/api"
-	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	toolscache "k8s.io/client-go/tools/cache"
 )
 
-type cloneSetHandler struct {
+// workloadHandler enqueues the executing task of the src or dst workload with the given kind.
+type workloadHandler struct {
 	ctrl *control
+	gvk  schema.GroupVersionKind
 }
 
-var _ toolscache.ResourceEventHandler = &cloneSetHandler{}
+var _ toolscache.ResourceEventHandler = &workloadHandler{}
 
-func (ch *cloneSetHandler) OnAdd(obj interface{}) {
-	d, ok := obj.(*appsv1alpha1.CloneSet)
-	if !ok {
-		return
-	}
-	ref := api.ResourceRef{
-		APIVersion: api.CloneSetKind.GroupVersion().String(),
-		Kind:       api.CloneSetKind.Kind,
-		Namespace:  d.Namespace,
-		Name:       d.Name,
-	}
-
-	ch.ctrl.RLock()
-	defer ch.ctrl.RUnlock()
-	if task, ok := ch.ctrl.executingTasks[ref]; ok {
-		ch.ctrl.queue.Add(task.ID)
-	}
+func (wh *workloadHandler) OnAdd(obj interface{}) {
+	wh.enqueue(obj)
 }
 
-func (ch *cloneSetHandler) OnUpdate(oldObj interface{}, newObj interface{}) {
-	d, ok := newObj.(*appsv1alpha1.CloneSet)
-	if !ok {
-		return
-	}
-
-	ref := api.ResourceRef{
-		APIVersion: api.CloneSetKind.GroupVersion().String(),
-		Kind:       api.CloneSetKind.Kind,
-		Namespace:  d.Namespace,
-		Name:       d.Name,
-	}
-
-	ch.ctrl.RLock()
-	defer ch.ctrl.RUnlock()
-	if task, ok := ch.ctrl.executingTasks[ref]; ok {
-		ch.ctrl.queue.Add(task.ID)
-	}
+func (wh *workloadHandler) OnUpdate(oldObj interface{}, newObj interface{}) {
+	wh.enqueue(newObj)
 }
 
-func (ch *cloneSetHandler) OnDelete(obj interface{}) {
-	d, ok := obj.(*appsv1alpha1.CloneSet)
-	if !ok {
-		return
-	}
-	ref := api.ResourceRef{
-		APIVersion: api.CloneSetKind.GroupVersion().String(),
-		Kind:       api.CloneSetKind.Kind,
-		Namespace:  d.Namespace,
-		Name:       d.Name,
-	}
-
-	ch.ctrl.RLock()
-	defer ch.ctrl.RUnlock()
-	if task, ok := ch.ctrl.executingTasks[ref]; ok {
-		ch.ctrl.queue.Add(task.ID)
-	}
-}
-
-type deploymentHandler struct {
-	ctrl *control
-}
-
-var _ toolscache.ResourceEventHandler = &deploymentHandler{}
-
-func (dh *deploymentHandler) OnAdd(obj interface{}) {
-	d, ok := obj.(*apps.Deployment)
-	if !ok {
-		return
-	}
-	ref := api.ResourceRef{
-		APIVersion: api.DeploymentKind.GroupVersion().String(),
-		Kind:       api.DeploymentKind.Kind,
-		Namespace:  d.Namespace,
-		Name:       d.Name,
-	}
-
-	dh.ctrl.RLock()
-	defer dh.ctrl.RUnlock()
-	if task, ok := dh.ctrl.executingTasks[ref]; ok {
-		dh.ctrl.queue.Add(task.ID)
-	}
-}
-
-func (dh *deploymentHandler) OnUpdate(oldObj interface{}, newObj interface{}) {
-	d, ok := newObj.(*apps.Deployment)
-	if !ok {
-		return
-	}
-
-	ref := api.ResourceRef{
-		APIVersion: api.DeploymentKind.GroupVersion().String(),
-		Kind:       api.DeploymentKind.Kind,
-		Namespace:  d.Namespace,
-		Name:       d.Name,
-	}
-
-	dh.ctrl.RLock()
-	defer dh.ctrl.RUnlock()
-	if task, ok := dh.ctrl.executingTasks[ref]; ok {
-		dh.ctrl.queue.Add(task.ID)
+func (wh *workloadHandler) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
 	}
+	wh.enqueue(obj)
 }
 
-func (dh *deploymentHandler) OnDelete(obj interface{}) {
-	d, ok := obj.(*apps.Deployment)
-	if !ok {
+func (wh *workloadHandler) enqueue(obj interface{}) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
 		return
 	}
 	ref := api.ResourceRef{
-		APIVersion: api.DeploymentKind.GroupVersion().String(),
-		Kind:       api.DeploymentKind.Kind,
-		Namespace:  d.Namespace,
-		Name:       d.Name,
+		APIVersion: wh.gvk.GroupVersion().String(),
+		Kind:       wh.gvk.Kind,
+		Namespace:  accessor.GetNamespace(),
+		Name:       accessor.GetName(),
 	}
 
-	dh.ctrl.RLock()
-	defer dh.ctrl.RUnlock()
-	if task, ok := dh.ctrl.executingTasks[ref]; ok {
-		dh.ctrl.queue.Add(task.ID)
+	wh.ctrl.RLock()
+	defer wh.ctrl.RUnlock()
+	if task, ok := wh.ctrl.executingTasks[ref]; ok {
+		wh.ctrl.queue.Add(task.ID)
 	}
 }
@@ -0,0 +1,196 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloneset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const eventSourceComponent = "kubectl-kruise-migration"
+
+// pendingStep is the last step of a task which pods are still changing.
+type pendingStep struct {
+	namespace string
+	selector  labels.Selector
+	// pods owned by dstUID if isDst, otherwise pods not owned by dstUID
+	isDst bool
+	pods  sets.String
+}
+
+func (c *control) Report(ID types.UID) (migration.Report, error) {
+	t := c.getTask(ID)
+	if t == nil {
+		return migration.Report{}, fmt.Errorf("not found ID %v", ID)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	report := t.report
+	report.Steps = append([]migration.Step{}, t.report.Steps...)
+	return report, nil
+}
+
+// stepPods returns the pods of the workload to compare with once the step ends. It must be called
+// before the workload is scaled, and the result may be nil if the pods cannot be listed.
+func (c *control) stepPods(t *task, ref api.ResourceRef, selector labels.Selector) sets.String {
+	pods, err := c.listPods(t, ref.Namespace, selector, ref == t.dst)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to list pods of %v: %v", ref, err))
+	}
+	return pods
+}
+
+// beginStep finishes the previous step and records a new step for the workload which has been scaled,
+// with pods returned by stepPods before it was scaled.
+func (c *control) beginStep(t *task, ref api.ResourceRef, uid types.UID, action migration.StepAction, from, to int32, selector labels.Selector, pods sets.String) {
+	c.endStep(t)
+
+	t.pendingStep = &pendingStep{namespace: ref.Namespace, selector: selector, isDst: ref == t.dst, pods: pods}
+
+	t.mu.Lock()
+	t.report.Steps = append(t.report.Steps, migration.Step{
+		Workload:     ref,
+		Action:       action,
+		FromReplicas: from,
+		ToReplicas:   to,
+		StartTime:    metav1.Now(),
+	})
+	t.mu.Unlock()
+
+	c.recordEvent(ref, uid, corev1.EventTypeNormal, "Migration"+string(action),
+		fmt.Sprintf("Migration %s scaled %s/%s from %d to %d replicas", t.ID, ref.Kind, ref.Name, from, to))
+}
+
+// endStep completes the last step with its waiting duration and the pods changed since it began.
+func (c *control) endStep(t *task) {
+	if t.pendingStep == nil {
+		return
+	}
+	ps := t.pendingStep
+	t.pendingStep = nil
+
+	pods, err := c.listPods(t, ps.namespace, ps.selector, ps.isDst)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to list pods for migration %v: %v", t.ID, err))
+	}
+
+	now := metav1.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	step := &t.report.Steps[len(t.report.Steps)-1]
+	step.FinishTime = &now
+	step.WaitingDuration = &metav1.Duration{Duration: now.Sub(step.StartTime.Time).Round(time.Millisecond)}
+	if ps.pods != nil && pods != nil {
+		step.CreatedPods = pods.Difference(ps.pods).List()
+		step.DeletedPods = ps.pods.Difference(pods).List()
+	}
+}
+
+// completeReport records the final state of the task into report and events on both workloads.
+func (c *control) completeReport(t *task, state migration.MigrateState, message string) {
+	c.endStep(t)
+
+	var srcReplicas, dstReplicas *int32
+	if srcObject, dstCloneSet, err := getSrcAndCloneSetObjects(c.client, &t.src, &t.dst); err == nil {
+		srcReplicas = getSrcReplicas(srcObject)
+		dstReplicas = dstCloneSet.Spec.Replicas
+	}
+
+	now := metav1.Now()
+	t.mu.Lock()
+	t.report.State = state
+	t.report.Message = message
+	t.report.FinishTime = &now
+	t.report.SrcMigratedReplicas = t.result.SrcMigratedReplicas
+	t.report.DstMigratedReplicas = t.result.DstMigratedReplicas
+	t.report.SrcReplicas = srcReplicas
+	t.report.DstReplicas = dstReplicas
+	t.mu.Unlock()
+
+	eventType, reason := corev1.EventTypeNormal, "MigrationSucceeded"
+	eventMessage := fmt.Sprintf("Migration %s from %s/%s to %s/%s succeeded with %d replicas in %d steps",
+		t.ID, t.src.Kind, t.src.Name, t.dst.Kind, t.dst.Name, t.result.DstMigratedReplicas, len(t.report.Steps))
+	if state != migration.MigrateSucceeded {
+		eventType, reason = corev1.EventTypeWarning, "MigrationFailed"
+		eventMessage = fmt.Sprintf("Migration %s from %s/%s to %s/%s failed: %s",
+			t.ID, t.src.Kind, t.src.Name, t.dst.Kind, t.dst.Name, message)
+	}
+	c.recordEvent(t.src, t.srcUID, eventType, reason, eventMessage)
+	c.recordEvent(t.dst, t.dstUID, eventType, reason, eventMessage)
+}
+
+// listPods returns names of the active pods matching selector, which are owned by dst CloneSet if isDst,
+// otherwise not owned by dst CloneSet.
+func (c *control) listPods(t *task, namespace string, selector labels.Selector, isDst bool) (sets.String, error) {
+	podList := &corev1.PodList{}
+	if err := c.client.List(context.TODO(), podList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	names := sets.NewString()
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		owner := metav1.GetControllerOf(pod)
+		ownedByDst := owner != nil && owner.UID == t.dstUID
+		if ownedByDst == isDst {
+			names.Insert(pod.Name)
+		}
+	}
+	return names, nil
+}
+
+func (c *control) recordEvent(ref api.ResourceRef, uid types.UID, eventType, reason, message string) {
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    ref.Namespace,
+			GenerateName: ref.Name + ".",
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: ref.APIVersion,
+			Kind:       ref.Kind,
+			Namespace:  ref.Namespace,
+			Name:       ref.Name,
+			UID:        uid,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: eventSourceComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if err := c.client.Create(context.TODO(), event); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to record event %s on %v: %v", reason, ref, err))
+	}
+}
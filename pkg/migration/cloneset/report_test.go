@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloneset
+
+import (
+	"context"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestPod(name, ownerUID string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": "web"}}}
+	isController := true
+	if len(ownerUID) > 0 {
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps.kruise.io/v1alpha1", Kind: "CloneSet", Name: "web", UID: types.UID(ownerUID), Controller: &isController}}
+	}
+	return pod
+}
+
+func newTestControl() (*control, *task) {
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "src-uid"},
+		Spec:       apps.DeploymentSpec{Replicas: int32Ptr(1)},
+	}
+	cloneSet := &appsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-cs", UID: "dst-uid"},
+		Spec:       appsv1alpha1.CloneSetSpec{Replicas: int32Ptr(2)},
+	}
+	c := &control{client: fake.NewFakeClientWithScheme(api.GetScheme(), deployment, cloneSet,
+		newTestPod("web-a", ""), newTestPod("web-b", ""), newTestPod("web-cs-a", "dst-uid"))}
+	t := &task{
+		ID:     "migration-id",
+		src:    api.NewDeploymentRef("default", "web"),
+		dst:    api.NewCloneSetRef("default", "web-cs"),
+		srcUID: "src-uid",
+		dstUID: "dst-uid",
+		result: migration.Result{SrcMigratedReplicas: 1, DstMigratedReplicas: 1},
+	}
+	return c, t
+}
+
+func listEvents(t *testing.T, c *control) []corev1.Event {
+	eventList := &corev1.EventList{}
+	assert.NoError(t, c.client.List(context.TODO(), eventList, client.InNamespace("default")))
+	return eventList.Items
+}
+
+func TestReportSteps(t *testing.T) {
+	c, task := newTestControl()
+	selector := labels.SelectorFromSet(labels.Set{"app": "web"})
+
+	// scale out the dst, which creates a pod
+	pods := c.stepPods(task, task.dst, selector)
+	assert.Equal(t, []string{"web-cs-a"}, pods.List())
+	c.beginStep(task, task.dst, task.dstUID, migration.StepScaleOut, 1, 2, selector, pods)
+	assert.Len(t, task.report.Steps, 1)
+	assert.Nil(t, task.report.Steps[0].FinishTime)
+	assert.Len(t, listEvents(t, c), 1)
+	assert.NoError(t, c.client.Create(context.TODO(), newTestPod("web-cs-b", "dst-uid")))
+
+	// scale in the src, which deletes a pod and ends the previous step
+	pods = c.stepPods(task, task.src, selector)
+	assert.Equal(t, []string{"web-a", "web-b"}, pods.List())
+	c.beginStep(task, task.src, task.srcUID, migration.StepScaleIn, 2, 1, selector, pods)
+	assert.Len(t, task.report.Steps, 2)
+	assert.NotNil(t, task.report.Steps[0].FinishTime)
+	assert.NotNil(t, task.report.Steps[0].WaitingDuration)
+	assert.Equal(t, []string{"web-cs-b"}, task.report.Steps[0].CreatedPods)
+	assert.Empty(t, task.report.Steps[0].DeletedPods)
+	assert.NoError(t, c.client.Delete(context.TODO(), newTestPod("web-b", "")))
+
+	c.endStep(task)
+	assert.Nil(t, task.pendingStep)
+	assert.Equal(t, migration.StepScaleIn, task.report.Steps[1].Action)
+	assert.Equal(t, []string{"web-b"}, task.report.Steps[1].DeletedPods)
+	assert.Empty(t, task.report.Steps[1].CreatedPods)
+
+	// ending again without a pending step changes nothing
+	c.endStep(task)
+	assert.Len(t, task.report.Steps, 2)
+}
+
+func TestCompleteReport(t *testing.T) {
+	c, task := newTestControl()
+	c.completeReport(task, migration.MigrateSucceeded, "")
+	assert.Equal(t, migration.MigrateSucceeded, task.report.State)
+	assert.NotNil(t, task.report.FinishTime)
+	assert.Equal(t, int32(1), task.report.SrcMigratedReplicas)
+	assert.Equal(t, int32(1), task.report.DstMigratedReplicas)
+	assert.Equal(t, int32Ptr(1), task.report.SrcReplicas)
+	assert.Equal(t, int32Ptr(2), task.report.DstReplicas)
+
+	events := listEvents(t, c)
+	assert.Len(t, events, 2)
+	for _, event := range events {
+		assert.Equal(t, "MigrationSucceeded", event.Reason)
+		assert.Equal(t, corev1.EventTypeNormal, event.Type)
+	}
+
+	c, task = newTestControl()
+	c.completeReport(task, migration.MigrateFailed, "task timeout exceeded")
+	assert.Equal(t, migration.MigrateFailed, task.report.State)
+	assert.Equal(t, "task timeout exceeded", task.report.Message)
+	for _, event := range listEvents(t, c) {
+		assert.Equal(t, "MigrationFailed", event.Reason)
+		assert.Equal(t, corev1.EventTypeWarning, event.Type)
+		assert.Contains(t, event.Message, "task timeout exceeded")
+	}
+}
+
+func TestAllReplicasMigrated(t *testing.T) {
+	task := &task{opts: migration.Options{Replicas: int32Ptr(2)}}
+	task.result = migration.Result{SrcMigratedReplicas: 1, DstMigratedReplicas: 2}
+	assert.False(t, allReplicasMigrated(task))
+	task.result = migration.Result{SrcMigratedReplicas: 2, DstMigratedReplicas: 2}
+	assert.True(t, allReplicasMigrated(task))
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
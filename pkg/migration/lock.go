@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// LockAnnotation is the annotation written onto both src and dst workloads
+	// while a migration task is executing on them.
+	LockAnnotation = "kubectl.kruise.io/migration-lock"
+
+	// DefaultLockDurationSeconds is how long a lock stays valid without being renewed.
+	DefaultLockDurationSeconds int32 = 60
+)
+
+// Lock is a lease-style lock held by a migration task on a workload.
+type Lock struct {
+	// Holder identifies who is running the migration, like user@hostname.
+	Holder string `json:"holder"`
+	// TaskID is the ID of the migration task holding the lock.
+	TaskID types.UID `json:"taskID"`
+	// RenewTime is the last time the lock was acquired or renewed.
+	RenewTime metav1.Time `json:"renewTime"`
+	// ExpireTime is the time after which the lock can be taken over by others.
+	ExpireTime metav1.Time `json:"expireTime"`
+}
+
+// NewLock returns a lock for the task that expires after duration.
+func NewLock(holder string, taskID types.UID, duration time.Duration) *Lock {
+	now := time.Now()
+	return &Lock{
+		Holder:     holder,
+		TaskID:     taskID,
+		RenewTime:  metav1.NewTime(now),
+		ExpireTime: metav1.NewTime(now.Add(duration)),
+	}
+}
+
+// Renew extends the expiry of the lock by duration from now.
+func (l *Lock) Renew(duration time.Duration) {
+	now := time.Now()
+	l.RenewTime = metav1.NewTime(now)
+	l.ExpireTime = metav1.NewTime(now.Add(duration))
+}
+
+// IsExpired returns whether the lock has expired at the given time.
+func (l *Lock) IsExpired(now time.Time) bool {
+	return !now.Before(l.ExpireTime.Time)
+}
+
+func (l *Lock) String() string {
+	return fmt.Sprintf("%s (task %s, expires at %s)", l.Holder, l.TaskID, l.ExpireTime.Format(time.RFC3339))
+}
+
+// GetLock returns the migration lock on the object, or nil if there is none.
+func GetLock(obj metav1.Object) (*Lock, error) {
+	value, ok := obj.GetAnnotations()[LockAnnotation]
+	if !ok || len(value) == 0 {
+		return nil, nil
+	}
+	lock := &Lock{}
+	if err := json.Unmarshal([]byte(value), lock); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %v", LockAnnotation, err)
+	}
+	return lock, nil
+}
+
+// SetLock writes the migration lock into the annotations of the object.
+func SetLock(obj metav1.Object, lock *Lock) {
+	value, _ := json.Marshal(lock)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[LockAnnotation] = string(value)
+	obj.SetAnnotations(annotations)
+}
+
+// RemoveLock removes the migration lock from the annotations of the object.
+// It returns false if there is no lock on the object.
+func RemoveLock(obj metav1.Object) bool {
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[LockAnnotation]; !ok {
+		return false
+	}
+	delete(annotations, LockAnnotation)
+	obj.SetAnnotations(annotations)
+	return true
+}
+
+// CheckLock returns an error if the object is locked by another task and the lock has not expired.
+func CheckLock(obj metav1.Object, taskID types.UID) error {
+	lock, err := GetLock(obj)
+	if err != nil {
+		return err
+	}
+	if lock != nil && lock.TaskID != taskID && !lock.IsExpired(time.Now()) {
+		return fmt.Errorf("%s/%s is being migrated by %v", obj.GetNamespace(), obj.GetName(), lock)
+	}
+	return nil
+}
+
+// DefaultLockHolder returns user@hostname of the current process.
+func DefaultLockHolder() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil && len(u.Username) > 0 {
+		name = u.Username
+	}
+	hostname, err := os.Hostname()
+	if err != nil || len(hostname) == 0 {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s@%s", name, hostname)
+}
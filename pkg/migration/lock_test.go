@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLock(t *testing.T) {
+	obj := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx"}}
+
+	lock, err := GetLock(obj)
+	assert.NoError(t, err)
+	assert.Nil(t, lock)
+	assert.NoError(t, CheckLock(obj, "task-1"))
+
+	SetLock(obj, NewLock("alice@host", "task-1", time.Minute))
+	lock, err = GetLock(obj)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@host", lock.Holder)
+	assert.NoError(t, CheckLock(obj, "task-1"))
+
+	err = CheckLock(obj, "task-2")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "alice@host")
+
+	lock.ExpireTime = metav1.NewTime(time.Now().Add(-time.Second))
+	SetLock(obj, lock)
+	assert.NoError(t, CheckLock(obj, "task-2"))
+
+	assert.True(t, RemoveLock(obj))
+	assert.False(t, RemoveLock(obj))
+}
+
+func TestGetLockInvalid(t *testing.T) {
+	obj := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{LockAnnotation: "{"}}}
+	_, err := GetLock(obj)
+	assert.Error(t, err)
+}
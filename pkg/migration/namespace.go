@@ -0,0 +1,272 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	storageProvisionerAnnotation = "volume.beta.kubernetes.io/storage-provisioner"
+	selectedNodeAnnotation       = "volume.kubernetes.io/selected-node"
+)
+
+// Dependencies are the objects in the same namespace referenced by a pod template.
+type Dependencies struct {
+	ConfigMaps             sets.String
+	Secrets                sets.String
+	PersistentVolumeClaims sets.String
+	ServiceAccount         string
+}
+
+// GetPodTemplate returns the pod template of the workload.
+func GetPodTemplate(reader client.Reader, ref api.ResourceRef) (*corev1.PodTemplateSpec, error) {
+	var obj runtime.Object
+	switch ref.GetGroupVersionKind() {
+	case api.DeploymentKind:
+		obj = &apps.Deployment{}
+	case api.ReplicaSetKind:
+		obj = &apps.ReplicaSet{}
+	case api.ReplicationControllerKind:
+		obj = &corev1.ReplicationController{}
+	default:
+		return nil, fmt.Errorf("unsupported workload type %v", ref.GetGroupVersionKind())
+	}
+	if err := reader.Get(context.TODO(), ref.GetNamespacedName(), obj); err != nil {
+		return nil, fmt.Errorf("failed to get %v: %v", ref, err)
+	}
+
+	switch o := obj.(type) {
+	case *apps.Deployment:
+		return &o.Spec.Template, nil
+	case *apps.ReplicaSet:
+		return &o.Spec.Template, nil
+	case *corev1.ReplicationController:
+		if o.Spec.Template == nil {
+			return &corev1.PodTemplateSpec{}, nil
+		}
+		return o.Spec.Template, nil
+	}
+	return nil, nil
+}
+
+// GetDependencies returns the ConfigMaps, Secrets, PersistentVolumeClaims and ServiceAccount referenced by the pod template.
+func GetDependencies(template *corev1.PodTemplateSpec) Dependencies {
+	deps := Dependencies{ConfigMaps: sets.NewString(), Secrets: sets.NewString(), PersistentVolumeClaims: sets.NewString()}
+	spec := &template.Spec
+
+	if len(spec.ServiceAccountName) > 0 && spec.ServiceAccountName != "default" {
+		deps.ServiceAccount = spec.ServiceAccountName
+	}
+	for _, s := range spec.ImagePullSecrets {
+		deps.Secrets.Insert(s.Name)
+	}
+
+	for _, v := range spec.Volumes {
+		if v.ConfigMap != nil {
+			deps.ConfigMaps.Insert(v.ConfigMap.Name)
+		}
+		if v.Secret != nil {
+			deps.Secrets.Insert(v.Secret.SecretName)
+		}
+		if v.PersistentVolumeClaim != nil {
+			deps.PersistentVolumeClaims.Insert(v.PersistentVolumeClaim.ClaimName)
+		}
+		if v.Projected != nil {
+			for _, source := range v.Projected.Sources {
+				if source.ConfigMap != nil {
+					deps.ConfigMaps.Insert(source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					deps.Secrets.Insert(source.Secret.Name)
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				deps.ConfigMaps.Insert(env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				deps.Secrets.Insert(env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+		for _, envFrom := range c.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				deps.ConfigMaps.Insert(envFrom.ConfigMapRef.Name)
+			}
+			if envFrom.SecretRef != nil {
+				deps.Secrets.Insert(envFrom.SecretRef.Name)
+			}
+		}
+	}
+	return deps
+}
+
+// CopyDependencies copies the dependencies from src namespace into dst namespace.
+// Objects already existing in dst namespace or not found in src namespace are skipped.
+// PersistentVolumeClaims are copied as templates, i.e. new claims with the spec of src claims but not their volumes.
+// It returns the kind/name of objects copied.
+func CopyDependencies(c client.Client, deps Dependencies, srcNamespace, dstNamespace string) ([]string, error) {
+	var copied []string
+	copyObject := func(kind, name string, src runtime.Object, newDst func() runtime.Object) error {
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: srcNamespace, Name: name}, src); err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to get %s %s/%s: %v", kind, srcNamespace, name, err)
+		}
+		dst := newDst()
+		if dst == nil {
+			return nil
+		}
+		if err := c.Create(context.TODO(), dst); err != nil {
+			if errors.IsAlreadyExists(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to create %s %s/%s: %v", kind, dstNamespace, name, err)
+		}
+		copied = append(copied, fmt.Sprintf("%s/%s", kind, name))
+		return nil
+	}
+
+	for _, name := range deps.ConfigMaps.List() {
+		cm := &corev1.ConfigMap{}
+		if err := copyObject("ConfigMap", name, cm, func() runtime.Object {
+			return &corev1.ConfigMap{
+				ObjectMeta: copyObjectMeta(&cm.ObjectMeta, dstNamespace),
+				Data:       cm.Data,
+				BinaryData: cm.BinaryData,
+				Immutable:  cm.Immutable,
+			}
+		}); err != nil {
+			return copied, err
+		}
+	}
+
+	for _, name := range deps.Secrets.List() {
+		secret := &corev1.Secret{}
+		if err := copyObject("Secret", name, secret, func() runtime.Object {
+			// token secrets are generated for ServiceAccount in each namespace
+			if secret.Type == corev1.SecretTypeServiceAccountToken {
+				return nil
+			}
+			return &corev1.Secret{
+				ObjectMeta: copyObjectMeta(&secret.ObjectMeta, dstNamespace),
+				Type:       secret.Type,
+				Data:       secret.Data,
+				Immutable:  secret.Immutable,
+			}
+		}); err != nil {
+			return copied, err
+		}
+	}
+
+	for _, name := range deps.PersistentVolumeClaims.List() {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := copyObject("PersistentVolumeClaim", name, pvc, func() runtime.Object {
+			meta := copyObjectMeta(&pvc.ObjectMeta, dstNamespace)
+			// binding annotations belong to the volume of src claim
+			for k := range meta.Annotations {
+				if strings.HasPrefix(k, "pv.kubernetes.io/") || k == storageProvisionerAnnotation || k == selectedNodeAnnotation {
+					delete(meta.Annotations, k)
+				}
+			}
+			return &corev1.PersistentVolumeClaim{
+				ObjectMeta: meta,
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes:      pvc.Spec.AccessModes,
+					Selector:         pvc.Spec.Selector,
+					Resources:        pvc.Spec.Resources,
+					StorageClassName: pvc.Spec.StorageClassName,
+					VolumeMode:       pvc.Spec.VolumeMode,
+				},
+			}
+		}); err != nil {
+			return copied, err
+		}
+	}
+
+	if len(deps.ServiceAccount) > 0 {
+		sa := &corev1.ServiceAccount{}
+		if err := copyObject("ServiceAccount", deps.ServiceAccount, sa, func() runtime.Object {
+			return &corev1.ServiceAccount{
+				ObjectMeta:                   copyObjectMeta(&sa.ObjectMeta, dstNamespace),
+				ImagePullSecrets:             sa.ImagePullSecrets,
+				AutomountServiceAccountToken: sa.AutomountServiceAccountToken,
+			}
+		}); err != nil {
+			return copied, err
+		}
+	}
+
+	return copied, nil
+}
+
+func copyObjectMeta(from *metav1.ObjectMeta, namespace string) metav1.ObjectMeta {
+	annotations := make(map[string]string, len(from.Annotations))
+	for k, v := range from.Annotations {
+		if k == corev1.LastAppliedConfigAnnotation {
+			continue
+		}
+		annotations[k] = v
+	}
+	return metav1.ObjectMeta{
+		Namespace:   namespace,
+		Name:        from.Name,
+		Labels:      from.Labels,
+		Annotations: annotations,
+	}
+}
+
+// GetServicesSelectingPods returns the Services in the namespace which select pods with the labels.
+func GetServicesSelectingPods(reader client.Reader, namespace string, podLabels map[string]string) ([]corev1.Service, error) {
+	svcList := &corev1.ServiceList{}
+	if err := reader.List(context.TODO(), svcList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	var services []corev1.Service
+	for _, svc := range svcList.Items {
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(podLabels)) {
+			services = append(services, svc)
+		}
+	}
+	return services, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetDependencies(t *testing.T) {
+	template := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			ServiceAccountName: "app",
+			ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "registry"}},
+			Volumes: []corev1.Volume{
+				{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}}}},
+				{Name: "cert", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "app-cert"}}},
+				{Name: "data", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "app-data"}}},
+				{Name: "projected", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+					{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "projected-config"}}},
+				}}}},
+			},
+			InitContainers: []corev1.Container{{
+				Name:    "init",
+				EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "init-env"}}}},
+			}},
+			Containers: []corev1.Container{{
+				Name: "app",
+				Env: []corev1.EnvVar{
+					{Name: "A", Value: "a"},
+					{Name: "B", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "env-config"}, Key: "b"}}},
+				},
+			}},
+		},
+	}
+
+	deps := GetDependencies(template)
+	assert.Equal(t, "app", deps.ServiceAccount)
+	assert.Equal(t, []string{"app-config", "env-config", "projected-config"}, deps.ConfigMaps.List())
+	assert.Equal(t, []string{"app-cert", "init-env", "registry"}, deps.Secrets.List())
+	assert.Equal(t, []string{"app-data"}, deps.PersistentVolumeClaims.List())
+
+	template.Spec.ServiceAccountName = "default"
+	assert.Equal(t, "", GetDependencies(template).ServiceAccount)
+}
+
+func TestCopyDependencies(t *testing.T) {
+	storageClass := "fast"
+	objectMeta := func(namespace, name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": "web"}}
+	}
+	c := fake.NewFakeClientWithScheme(api.GetScheme(),
+		&corev1.ConfigMap{ObjectMeta: objectMeta("legacy", "app-config"), Data: map[string]string{"a": "1"}},
+		&corev1.ConfigMap{ObjectMeta: objectMeta("legacy", "shared-config"), Data: map[string]string{"b": "2"}},
+		&corev1.ConfigMap{ObjectMeta: objectMeta("apps", "shared-config"), Data: map[string]string{"b": "dst"}},
+		&corev1.Secret{ObjectMeta: objectMeta("legacy", "app-cert"), Type: corev1.SecretTypeTLS, Data: map[string][]byte{"tls.crt": []byte("cert")}},
+		&corev1.Secret{ObjectMeta: objectMeta("legacy", "app-token"), Type: corev1.SecretTypeServiceAccountToken},
+		&corev1.ServiceAccount{ObjectMeta: objectMeta("legacy", "app")},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "legacy",
+				Name:        "app-data",
+				Annotations: map[string]string{"pv.kubernetes.io/bind-completed": "yes", storageProvisionerAnnotation: "csi", "team": "web"},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources:        corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}},
+				StorageClassName: &storageClass,
+				VolumeName:       "pv-legacy",
+			},
+			Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		},
+	)
+
+	deps := Dependencies{
+		ConfigMaps:             sets.NewString("app-config", "shared-config", "missing-config"),
+		Secrets:                sets.NewString("app-cert", "app-token"),
+		PersistentVolumeClaims: sets.NewString("app-data"),
+		ServiceAccount:         "app",
+	}
+	copied, err := CopyDependencies(c, deps, "legacy", "apps")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ConfigMap/app-config", "Secret/app-cert", "PersistentVolumeClaim/app-data", "ServiceAccount/app"}, copied)
+
+	get := func(name string, obj runtime.Object) error {
+		return c.Get(context.TODO(), types.NamespacedName{Namespace: "apps", Name: name}, obj)
+	}
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, get("app-config", cm))
+	assert.Equal(t, map[string]string{"a": "1"}, cm.Data)
+	assert.Equal(t, map[string]string{"app": "web"}, cm.Labels)
+
+	// existing objects in dst namespace are left untouched
+	cm = &corev1.ConfigMap{}
+	assert.NoError(t, get("shared-config", cm))
+	assert.Equal(t, map[string]string{"b": "dst"}, cm.Data)
+
+	secret := &corev1.Secret{}
+	assert.NoError(t, get("app-cert", secret))
+	assert.Equal(t, corev1.SecretTypeTLS, secret.Type)
+	assert.Equal(t, []byte("cert"), secret.Data["tls.crt"])
+
+	// token secrets are generated in dst namespace for the service account
+	assert.Error(t, get("app-token", secret))
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	assert.NoError(t, get("app-data", pvc))
+	assert.Equal(t, map[string]string{"team": "web"}, pvc.Annotations)
+	assert.Equal(t, "", pvc.Spec.VolumeName)
+	assert.Equal(t, &storageClass, pvc.Spec.StorageClassName)
+	assert.Equal(t, resource.MustParse("1Gi"), pvc.Spec.Resources.Requests[corev1.ResourceStorage])
+	assert.Equal(t, corev1.PersistentVolumeClaimStatus{}, pvc.Status)
+
+	assert.NoError(t, get("app", &corev1.ServiceAccount{}))
+
+	// copying again skips all the objects already copied
+	copied, err = CopyDependencies(c, deps, "legacy", "apps")
+	assert.NoError(t, err)
+	assert.Empty(t, copied)
+}